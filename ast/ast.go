@@ -2,6 +2,8 @@
 package ast
 
 import (
+	"math/big"
+
 	"github.com/issadicko/kodi-script-go/token"
 )
 
@@ -34,26 +36,43 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
-// VarDecl represents a variable declaration: let x = expr
+// VarDecl represents a variable declaration: let x = expr or const x = expr, or a
+// destructuring declaration such as let {name, tier} = user or let [a, b] = pair.
 type VarDecl struct {
-	Token token.Token // the LET token
-	Name  *Identifier
-	Value Expression
+	Token   token.Token // the LET or CONST token
+	Name    *Identifier // nil when Pattern is set
+	Pattern Expression  // *ObjectPattern or *ArrayPattern, can be nil
+	Value   Expression
+	Const   bool   // declared with const: the binding cannot be reassigned
+	Doc     string // text of the /// comments above the declaration
 }
 
 func (v *VarDecl) statementNode()       {}
 func (v *VarDecl) TokenLiteral() string { return v.Token.Literal }
 
-// Assignment represents an assignment: x = expr
+// Assignment represents an assignment: x = expr, or a compound one such as x += expr.
+// Updates (x++, x--) are parsed as x += 1 and x -= 1.
 type Assignment struct {
-	Token token.Token // the IDENT token
-	Name  *Identifier
-	Value Expression
+	Token    token.Token // the IDENT token
+	Name     *Identifier
+	Operator string // "=" or a compound operator ("+=", "-=", "*=", "/=", "%=")
+	Value    Expression
 }
 
 func (a *Assignment) statementNode()       {}
 func (a *Assignment) TokenLiteral() string { return a.Token.Literal }
 
+// MemberAssignment represents an assignment to a property or index: obj.key = expr, arr[i] += expr
+type MemberAssignment struct {
+	Token    token.Token // the assignment operator token
+	Target   Expression  // *PropertyAccessExpr or *IndexExpr
+	Operator string      // "=" or a compound operator ("+=", "-=", "*=", "/=", "%=")
+	Value    Expression
+}
+
+func (ma *MemberAssignment) statementNode()       {}
+func (ma *MemberAssignment) TokenLiteral() string { return ma.Token.Literal }
+
 // ExpressionStatement wraps an expression as a statement.
 type ExpressionStatement struct {
 	Token      token.Token
@@ -64,6 +83,7 @@ func (es *ExpressionStatement) statementNode()       {}
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
 
 // IfStatement represents: if (condition) { consequence } else { alternative }
+// An else-if chain is stored as an Alternative holding a single nested IfStatement.
 type IfStatement struct {
 	Token       token.Token // the IF token
 	Condition   Expression
@@ -92,20 +112,72 @@ type ReturnStatement struct {
 func (rs *ReturnStatement) statementNode()       {}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
 
-// ForStatement represents: for (variable in iterable) { body }
+// FunctionDecl represents a named function declaration: fn name(x, y = 1) { ... }
+// Declarations are hoisted to the top of their enclosing block.
+type FunctionDecl struct {
+	Token    token.Token // the 'fn' token
+	Name     *Identifier
+	Function *FunctionLiteral
+	Doc      string // text of the /// comments above the declaration
+}
+
+func (fd *FunctionDecl) statementNode()       {}
+func (fd *FunctionDecl) TokenLiteral() string { return fd.Token.Literal }
+
+// ImportStatement represents: import { name, other as alias } from "module"
+type ImportStatement struct {
+	Token      token.Token // the IMPORT token
+	Specifiers []*ImportSpecifier
+	Source     string // module name passed to the resolver
+}
+
+func (is *ImportStatement) statementNode()       {}
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+
+// ImportSpecifier is one imported name, optionally renamed with 'as'.
+type ImportSpecifier struct {
+	Name  *Identifier // name exported by the module
+	Alias *Identifier // local name, nil when not renamed
+}
+
+// LocalName returns the name the import is bound to in the importing script.
+func (s *ImportSpecifier) LocalName() *Identifier {
+	if s.Alias != nil {
+		return s.Alias
+	}
+	return s.Name
+}
+
+// ExportStatement represents an exported declaration:
+// export let x = ..., export const y = ... or export fn name() { }
+type ExportStatement struct {
+	Token       token.Token // the EXPORT token
+	Declaration Statement   // *VarDecl or *FunctionDecl
+}
+
+func (es *ExportStatement) statementNode()       {}
+func (es *ExportStatement) TokenLiteral() string { return es.Token.Literal }
+
+// ForStatement represents: [label:] for (variable in iterable) { body }
+// With two variables, for (i, item in arr) also binds the index, and
+// for (key, value in obj) binds each key and value of an object.
 type ForStatement struct {
 	Token    token.Token     // the FOR token
-	Variable *Identifier     // loop variable
-	Iterable Expression      // expression that produces an array
+	Label    *Identifier     // optional loop label, can be nil
+	Index    *Identifier     // index or key variable in the two-variable form, can be nil
+	Variable *Identifier     // loop variable, nil when Pattern is set
+	Pattern  Expression      // destructuring loop variable, can be nil
+	Iterable Expression      // expression that produces an array, a range or an object
 	Body     *BlockStatement // loop body
 }
 
 func (fs *ForStatement) statementNode()       {}
 func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
 
-// WhileStatement represents: while (condition) { body }
+// WhileStatement represents: [label:] while (condition) { body }
 type WhileStatement struct {
 	Token     token.Token     // the WHILE token
+	Label     *Identifier     // optional loop label, can be nil
 	Condition Expression      // loop condition
 	Body      *BlockStatement // loop body
 }
@@ -113,6 +185,77 @@ type WhileStatement struct {
 func (ws *WhileStatement) statementNode()       {}
 func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
 
+// BreakStatement represents: break [label]
+type BreakStatement struct {
+	Token token.Token // the BREAK token
+	Label *Identifier // optional, can be nil
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+
+// ContinueStatement represents: continue [label]
+type ContinueStatement struct {
+	Token token.Token // the CONTINUE token
+	Label *Identifier // optional, can be nil
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+
+// TryStatement represents: try { block } catch (param) { handler } finally { finalizer }
+// At least one of Handler and Finalizer is set; Param is optional.
+type TryStatement struct {
+	Token     token.Token // the TRY token
+	Block     *BlockStatement
+	Param     *Identifier     // catch variable, can be nil
+	Handler   *BlockStatement // catch block, can be nil
+	Finalizer *BlockStatement // finally block, can be nil
+}
+
+func (ts *TryStatement) statementNode()       {}
+func (ts *TryStatement) TokenLiteral() string { return ts.Token.Literal }
+
+// ThrowStatement represents: throw expr
+type ThrowStatement struct {
+	Token token.Token // the THROW token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+
+// ObjectPattern represents an object destructuring pattern: {name, tier: level = "basic", address: {city}}
+type ObjectPattern struct {
+	Token      token.Token // the '{' token
+	Properties []*PatternProperty
+}
+
+func (op *ObjectPattern) expressionNode()      {}
+func (op *ObjectPattern) TokenLiteral() string { return op.Token.Literal }
+
+// PatternProperty binds one key of an ObjectPattern.
+type PatternProperty struct {
+	Key     string
+	Target  Expression // *Identifier, *ObjectPattern or *ArrayPattern
+	Default Expression // used when the value is missing or null, can be nil
+}
+
+// ArrayPattern represents an array destructuring pattern: [first, second = 0, [x, y]]
+type ArrayPattern struct {
+	Token    token.Token // the '[' token
+	Elements []*PatternElement
+}
+
+func (ap *ArrayPattern) expressionNode()      {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+
+// PatternElement binds one position of an ArrayPattern.
+type PatternElement struct {
+	Target  Expression // *Identifier, *ObjectPattern or *ArrayPattern
+	Default Expression // used when the value is missing or null, can be nil
+}
+
 // Identifier represents a variable name.
 type Identifier struct {
 	Token token.Token // the IDENT token
@@ -122,7 +265,7 @@ type Identifier struct {
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 
-// NumberLiteral represents a numeric value.
+// NumberLiteral represents a floating-point value: 1.5, or an integer too large for an int64.
 type NumberLiteral struct {
 	Token token.Token
 	Value float64
@@ -131,6 +274,24 @@ type NumberLiteral struct {
 func (nl *NumberLiteral) expressionNode()      {}
 func (nl *NumberLiteral) TokenLiteral() string { return nl.Token.Literal }
 
+// IntegerLiteral represents an integer value: 42
+type IntegerLiteral struct {
+	Token token.Token
+	Value int64
+}
+
+func (il *IntegerLiteral) expressionNode()      {}
+func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+
+// DecimalLiteral represents an exact decimal value: 19.99d
+type DecimalLiteral struct {
+	Token token.Token
+	Value *big.Rat
+}
+
+func (dl *DecimalLiteral) expressionNode()      {}
+func (dl *DecimalLiteral) TokenLiteral() string { return dl.Token.Literal }
+
 // StringLiteral represents a string value.
 type StringLiteral struct {
 	Token token.Token
@@ -175,30 +336,52 @@ type ArrayLiteral struct {
 func (al *ArrayLiteral) expressionNode()      {}
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
 
-// ObjectLiteral represents an object: {key: value, ...}
+// ObjectLiteral represents an object: {key: value, ...other}
 type ObjectLiteral struct {
-	Token token.Token           // the '{' token
-	Pairs map[string]Expression // key-value pairs
+	Token   token.Token   // the '{' token
+	Entries []ObjectEntry // pairs and spreads in source order
+}
+
+// ObjectEntry is one entry of an object literal. Spread entries have an
+// empty Key and a *SpreadExpr Value.
+type ObjectEntry struct {
+	Key   string
+	Value Expression
 }
 
 func (ol *ObjectLiteral) expressionNode()      {}
 func (ol *ObjectLiteral) TokenLiteral() string { return ol.Token.Literal }
 
-// FunctionLiteral represents a function definition: fn(x, y) { ... }
+// FunctionLiteral represents a function definition: fn(x, y = 10, ...rest) { ... }
+// Lambdas (x => expr, (a, b) => { ... }) produce the same node; an expression
+// body is stored as a block holding a single return statement.
 type FunctionLiteral struct {
-	Token      token.Token // The 'fn' token
+	Token      token.Token // The 'fn' or '=>' token
 	Parameters []*Identifier
+	Defaults   []Expression // default value for each parameter, nil entries when absent
+	Rest       *Identifier  // rest parameter collecting extra arguments, can be nil
 	Body       *BlockStatement
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
 
-// IndexExpr represents array/object index access: arr[0] or obj["key"]
+// SpreadExpr represents a spread: ...expr inside an array literal, object literal or call arguments
+type SpreadExpr struct {
+	Token token.Token // the ... token
+	Value Expression
+}
+
+func (se *SpreadExpr) expressionNode()      {}
+func (se *SpreadExpr) TokenLiteral() string { return se.Token.Literal }
+
+// IndexExpr represents array/object index access: arr[0] or obj["key"].
+// Optional marks the null-safe form arr?.[0].
 type IndexExpr struct {
-	Token token.Token // the '[' token
-	Left  Expression  // the array or object
-	Index Expression  // the index or key
+	Token    token.Token // the '[' token
+	Left     Expression  // the array or object
+	Index    Expression  // the index or key
+	Optional bool
 }
 
 func (ie *IndexExpr) expressionNode()      {}
@@ -225,7 +408,8 @@ type UnaryExpr struct {
 func (ue *UnaryExpr) expressionNode()      {}
 func (ue *UnaryExpr) TokenLiteral() string { return ue.Token.Literal }
 
-// SafeAccessExpr represents optional chaining: obj?.property
+// SafeAccessExpr represents optional chaining: obj?.property. When obj is
+// null, the rest of the chain (obj?.a.b(), obj?.a[0]) is skipped and yields null.
 type SafeAccessExpr struct {
 	Token    token.Token // the ?. token
 	Object   Expression
@@ -245,6 +429,17 @@ type ElvisExpr struct {
 func (ee *ElvisExpr) expressionNode()      {}
 func (ee *ElvisExpr) TokenLiteral() string { return ee.Token.Literal }
 
+// ConditionalExpr represents the ternary operator: condition ? consequence : alternative
+type ConditionalExpr struct {
+	Token       token.Token // the ? token
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (ce *ConditionalExpr) expressionNode()      {}
+func (ce *ConditionalExpr) TokenLiteral() string { return ce.Token.Literal }
+
 // PropertyAccessExpr represents property access: obj.property
 type PropertyAccessExpr struct {
 	Token    token.Token // the . token
@@ -260,7 +455,38 @@ type CallExpr struct {
 	Token     token.Token // the LPAREN token
 	Function  Expression  // Identifier or expression
 	Arguments []Expression
+	Optional  bool // fn?.(args): null when the function is null
 }
 
 func (ce *CallExpr) expressionNode()      {}
 func (ce *CallExpr) TokenLiteral() string { return ce.Token.Literal }
+
+// MatchExpr represents: match (subject) { pattern, pattern -> result, else -> result }
+type MatchExpr struct {
+	Token   token.Token // the MATCH token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpr) expressionNode()      {}
+func (me *MatchExpr) TokenLiteral() string { return me.Token.Literal }
+
+// MatchArm represents a single arm of a match expression.
+// Patterns is nil for the else arm.
+type MatchArm struct {
+	Token    token.Token // the first token of the arm
+	Patterns []Expression
+	Body     *BlockStatement // expression bodies are wrapped in a single-statement block
+}
+
+// RangeExpr represents a range: start..end (inclusive) or start..<end (exclusive).
+// It evaluates to a lazy range, also usable as a match pattern.
+type RangeExpr struct {
+	Token     token.Token // the .. or ..< token
+	Start     Expression
+	End       Expression
+	Inclusive bool
+}
+
+func (re *RangeExpr) expressionNode()      {}
+func (re *RangeExpr) TokenLiteral() string { return re.Token.Literal }
@@ -1,8 +1,10 @@
 package kodi
 
 import (
+	"math/big"
 	"strings"
 	"testing"
+	"time"
 )
 
 // Test structs for reflective binding
@@ -165,7 +167,7 @@ func TestBindNumericConversion(t *testing.T) {
 	}
 }
 
-// TestBindIntReturn tests that ints are converted to float64
+// TestBindIntReturn tests that ints are converted to int64
 func TestBindIntReturn(t *testing.T) {
 	user := &User{Age: 42}
 
@@ -176,9 +178,9 @@ func TestBindIntReturn(t *testing.T) {
 		t.Fatalf("Unexpected errors: %v", result.Errors)
 	}
 
-	// KodiScript uses float64 for numbers
-	if result.Value != 42.0 {
-		t.Errorf("Expected 42.0, got %v", result.Value)
+	// Go integers become KodiScript integers
+	if result.Value != int64(42) {
+		t.Errorf("Expected 42, got %v (%T)", result.Value, result.Value)
 	}
 }
 
@@ -203,7 +205,7 @@ func TestBindMultipleObjects(t *testing.T) {
 		t.Fatalf("Unexpected errors: %v", result.Errors)
 	}
 
-	expected := "Hello, I'm Frank 8"
+	expected := "Hello, I'm Frank 8.0"
 	if result.Value != expected {
 		t.Errorf("Expected '%s', got %v", expected, result.Value)
 	}
@@ -304,3 +306,497 @@ func TestBindInLoop(t *testing.T) {
 		t.Errorf("Expected 15.0, got %v", result.Value)
 	}
 }
+
+// TestBindFieldAssignment tests setting exported fields on bound objects
+func TestBindFieldAssignment(t *testing.T) {
+	user := &User{Name: "Alice", Age: 30, Address: Address{City: "Paris"}}
+
+	result := New(`user.Name = "Bob"
+user.Age = 41
+user.Address.City = "Lyon"
+user.SayHello()`).Bind("user", user).Execute()
+
+	if len(result.Errors) > 0 {
+		t.Fatalf("Unexpected errors: %v", result.Errors)
+	}
+	if result.Value != "Hello, I'm Bob" {
+		t.Errorf("Expected 'Hello, I'm Bob', got %v", result.Value)
+	}
+	if user.Age != 41 {
+		t.Errorf("Expected Age 41, got %d", user.Age)
+	}
+	if user.Address.City != "Lyon" {
+		t.Errorf("Expected City 'Lyon', got %s", user.Address.City)
+	}
+}
+
+// TestBindFieldAssignmentTypeMismatch tests that invalid conversions are reported
+func TestBindFieldAssignmentTypeMismatch(t *testing.T) {
+	user := &User{Name: "Alice"}
+
+	result := New(`user.Age = "old"`).Bind("user", user).Execute()
+
+	if len(result.Errors) == 0 {
+		t.Fatal("Expected error when assigning a string to an int field")
+	}
+	if !strings.Contains(result.Errors[0], "Age") {
+		t.Errorf("Expected error to mention field name, got: %s", result.Errors[0])
+	}
+}
+
+// TestBindDestructuring tests destructuring fields of bound objects
+func TestBindDestructuring(t *testing.T) {
+	user := &User{Name: "Alice", Age: 30, Address: Address{City: "Paris"}}
+
+	result := New(`let {Name, Address: {City: city}} = user
+Name + " - " + city`).Bind("user", user).Execute()
+
+	if len(result.Errors) > 0 {
+		t.Fatalf("Unexpected errors: %v", result.Errors)
+	}
+	if result.Value != "Alice - Paris" {
+		t.Errorf("Expected 'Alice - Paris', got %v", result.Value)
+	}
+}
+
+// TestBindGoCollections tests indexing, iterating and sizing typed Go slices, arrays and maps
+func TestBindGoCollections(t *testing.T) {
+	vars := map[string]interface{}{
+		"users": []User{
+			{Name: "Alice", Age: 30, Address: Address{City: "Paris"}},
+			{Name: "Bob", Age: 25, Address: Address{City: "Lyon"}},
+		},
+		"tags":   []string{"go", "kodi"},
+		"ids":    [3]int{7, 8, 9},
+		"prices": map[string]float64{"apple": 1.5, "pear": 2},
+		"stock":  map[string]int{"apple": 3},
+		"admins": &[]*User{{Name: "Root"}},
+		"rates":  []big.Rat{*big.NewRat(1, 4)},
+		"maybe":  []*User{nil},
+	}
+
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{`users[1].Name`, "Bob"},
+		{`users[0].Address.City`, "Paris"},
+		{`users[5]`, nil},
+		{`tags[0] + "/" + tags[1]`, "go/kodi"},
+		{`ids[2]`, int64(9)},
+		{`ids[1] + 1`, int64(9)},
+		{`size(users) + size(tags) + size(ids) + size(prices)`, int64(9)},
+		{`prices.apple + prices["pear"]`, 3.5},
+		{`prices.banana`, nil},
+		{`stock.apple * 2`, int64(6)},
+		{`admins[0].Name`, "Root"},
+		{`let total = 0
+for (u in users) { total += u.Age }
+total`, int64(55)},
+		{`let out = ""
+for (idx, tag in tags) { out += idx + ":" + tag + " " }
+out`, "0:go 1:kodi "},
+		{`let out = ""
+for (name in prices) { out += name + " " }
+out`, "apple pear "},
+		{`let sum = 0
+for (name, price in prices) { sum += price }
+sum`, 3.5},
+		{`let {apple} = stock
+apple`, int64(3)},
+		{`let [first, second] = tags
+second`, "kodi"},
+		{`join(map(users, u => u.Name), ", ")`, "Alice, Bob"},
+		{`size(filter(ids, n => n > 7))`, int64(2)},
+		{`reduce(ids, (acc, n) => acc + n, 0)`, int64(24)},
+		{`find(users, u => u.Age < 30).Name`, "Bob"},
+		{`findIndex(tags, t => t == "kodi")`, int64(1)},
+		{`sortBy(users, "Age")[0].Name`, "Bob"},
+		{`sortBy(users, "Name", "desc")[0].Name`, "Bob"},
+		{`first(reverse(ids))`, int64(9)},
+		{`last(sort(tags, "desc"))`, "go"},
+		{`join(tags, "-")`, "go-kodi"},
+		{`size(slice(ids, 1))`, int64(2)},
+		{`size([...tags, "x"])`, int64(3)},
+		// Natives and indexing read host elements the same way
+		{`typeOf(first(rates)) + " " + typeOf(rates[0])`, "decimal decimal"},
+		{`first(rates) == rates[0]`, true},
+		{`first(maybe) == null && maybe[0] == null`, true},
+	}
+
+	for _, tt := range tests {
+		result := New(tt.source).WithVariables(vars).Execute()
+		if len(result.Errors) > 0 {
+			t.Errorf("%s: unexpected errors: %v", tt.source, result.Errors)
+			continue
+		}
+		if result.Value != tt.expected {
+			t.Errorf("%s: expected %v, got %v (%T)", tt.source, tt.expected, result.Value, result.Value)
+		}
+	}
+}
+
+// TestBindGoCollectionErrors tests the errors reported for invalid access to Go collections
+func TestBindGoCollectionErrors(t *testing.T) {
+	vars := map[string]interface{}{
+		"tags":  []string{"go"},
+		"stock": map[string]int{"apple": 3},
+		"codes": map[int]string{1: "one"},
+	}
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{`tags["x"]`, "index must be a number"},
+		{`stock[0]`, "property access must be a string"},
+		{`for (c in codes) {}`, "for-in requires an array, a range or an object"},
+	}
+
+	for _, tt := range tests {
+		result := New(tt.source).WithVariables(vars).Execute()
+		if len(result.Errors) == 0 {
+			t.Errorf("%s: expected an error", tt.source)
+			continue
+		}
+		if !strings.Contains(result.Errors[0], tt.expected) {
+			t.Errorf("%s: expected error containing %q, got %q", tt.source, tt.expected, result.Errors[0])
+		}
+	}
+}
+
+// TestBindGoCollectionAssignment tests assigning into typed Go slices, arrays and maps
+func TestBindGoCollectionAssignment(t *testing.T) {
+	type Profile struct {
+		Tags  []string
+		Count uint8
+		Level int8
+	}
+	profile := &Profile{Tags: []string{"x"}}
+	stock := map[string]int{"apple": 3}
+
+	result := New(`profile.Tags[0] = "y"
+profile.Tags[size(profile.Tags)] = "z"
+stock.apple = 5
+stock["pear"] = 2.0
+ids[0] = 1
+profile.Count = 255
+profile.Level = -128
+ids[0] + ids[1]`).
+		Bind("profile", profile).
+		WithVariables(map[string]interface{}{"stock": stock, "ids": [2]int{7, 8}}).
+		Execute()
+	if len(result.Errors) > 0 {
+		t.Fatalf("Unexpected errors: %v", result.Errors)
+	}
+	if result.Value != int64(9) {
+		t.Errorf("Expected 9, got %v", result.Value)
+	}
+	if len(profile.Tags) != 2 || profile.Tags[0] != "y" || profile.Tags[1] != "z" {
+		t.Errorf("Expected tags [y z], got %v", profile.Tags)
+	}
+	if stock["apple"] != 5 || stock["pear"] != 2 {
+		t.Errorf("Expected stock updated in place, got %v", stock)
+	}
+	if profile.Count != 255 || profile.Level != -128 {
+		t.Errorf("Expected 255 and -128, got %d and %d", profile.Count, profile.Level)
+	}
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{`profile.Count = -1`, "-1 is out of range for uint8"},
+		{`profile.Count = 256`, "256 is out of range for uint8"},
+		{`profile.Level = 128`, "128 is out of range for int8"},
+		{`profile.Level = -200.5`, "-200 is out of range for int8"},
+		{`profile.Tags[0] = 5`, "cannot convert int64 to string"},
+		{`profile.Tags[5] = "x"`, "array index out of range: 5 (length 2)"},
+		{`stock[1] = 2`, "object key must be a string"},
+	}
+	for _, tt := range tests {
+		result := New(tt.source).
+			Bind("profile", profile).
+			WithVariables(map[string]interface{}{"stock": stock}).
+			Execute()
+		if len(result.Errors) == 0 {
+			t.Errorf("%s: expected an error", tt.source)
+			continue
+		}
+		if !strings.Contains(result.Errors[0], tt.expected) {
+			t.Errorf("%s: expected error containing %q, got %q", tt.source, tt.expected, result.Errors[0])
+		}
+	}
+}
+
+// TestBindOptionalChaining tests null-safe access on bound objects
+func TestBindOptionalChaining(t *testing.T) {
+	user := &User{Name: "Alice", Address: Address{City: "Paris"}}
+
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{`user?.Name`, "Alice"},
+		{`user?.Address?.City`, "Paris"},
+		{`user?.SayHello()`, "Hello, I'm Alice"},
+		{`user?.Greet?.("Hi")`, "Hi, Alice!"},
+		{`users?.[1]?.Name`, nil},
+		{`users?.[0]?.Name`, "Alice"},
+		{`nobody?.Address.City`, nil},
+		{`nobody?.SayHello()`, nil},
+	}
+
+	for _, tt := range tests {
+		result := New(tt.source).
+			Bind("user", user).
+			WithVariables(map[string]interface{}{"users": []*User{user}, "nobody": nil}).
+			Execute()
+		if len(result.Errors) > 0 {
+			t.Errorf("%s: unexpected errors: %v", tt.source, result.Errors)
+			continue
+		}
+		if result.Value != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.source, tt.expected, result.Value)
+		}
+	}
+
+	result := New(`user?.Missing`).Bind("user", user).Execute()
+	if len(result.Errors) == 0 || !strings.Contains(result.Errors[0], "Missing") {
+		t.Errorf("Expected error for unknown property, got %v", result.Errors)
+	}
+}
+
+type Order struct {
+	ID        int64
+	Snowflake uint64
+	Quantity  int
+}
+
+func (o *Order) SameID(id int64) bool {
+	return id == o.ID
+}
+
+func (o *Order) SameSnowflake(id uint64) bool {
+	return id == o.Snowflake
+}
+
+type Event struct {
+	Name string
+	At   time.Time
+}
+
+// TestBindOrdering tests comparisons of host times and collated strings
+func TestBindOrdering(t *testing.T) {
+	jan := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	vars := map[string]interface{}{
+		"events":   []Event{{"launch", feb}, {"kickoff", jan}},
+		"deadline": feb,
+	}
+
+	result := New(`
+		let early = filter(events, e => e.At < deadline)
+		join(map(sortBy(events, "At"), e => e.Name), ",") + " " + size(early)
+	`).WithVariables(vars).Execute()
+	if len(result.Errors) > 0 {
+		t.Fatalf("Unexpected errors: %v", result.Errors)
+	}
+	if result.Value != "kickoff,launch 1" {
+		t.Errorf("Expected 'kickoff,launch 1', got %v", result.Value)
+	}
+
+	ignoreCase := func(a, b string) int {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	}
+	source := `join(sort(["banana", "Cherry", "apple"]), ",") + " " + ("Zebra" > "apple")`
+
+	result = New(source).Execute()
+	if len(result.Errors) > 0 {
+		t.Fatalf("Unexpected errors: %v", result.Errors)
+	}
+	if result.Value != "Cherry,apple,banana false" {
+		t.Errorf("Expected byte order, got %v", result.Value)
+	}
+
+	result = New(source).WithCollation(ignoreCase).Execute()
+	if len(result.Errors) > 0 {
+		t.Fatalf("Unexpected errors: %v", result.Errors)
+	}
+	if result.Value != "apple,banana,Cherry true" {
+		t.Errorf("Expected collated order, got %v", result.Value)
+	}
+
+	result = New(`sort([3, 1, 2]) + " " + max("b", "C")`).
+		RegisterFunction("sort", func(args ...interface{}) (interface{}, error) {
+			return "custom", nil
+		}).
+		WithCollation(ignoreCase).
+		Execute()
+	if len(result.Errors) > 0 {
+		t.Fatalf("Unexpected errors: %v", result.Errors)
+	}
+	if result.Value != "custom C" {
+		t.Errorf("Expected the custom sort to survive the collation, got %v", result.Value)
+	}
+}
+
+// TestBindDeepEquality tests that host values compare structurally with script values
+func TestBindDeepEquality(t *testing.T) {
+	vars := map[string]interface{}{
+		"count":  int(3),
+		"ratio":  float64(3),
+		"ids":    []int{1, 2, 3},
+		"scores": map[string]int{"alice": 10},
+		"nested": map[string]interface{}{"tags": []string{"a"}},
+		"grid":   [2][]int{{1}, {2}},
+		"alice":  Address{City: "Paris", Country: "FR"},
+		"paris":  &Address{City: "Paris", Country: "FR"},
+	}
+
+	tests := []struct {
+		source   string
+		expected bool
+	}{
+		{`count == ratio`, true},
+		{`ids == [1, 2, 3]`, true},
+		{`ids == [1, 2]`, false},
+		{`scores == {alice: 10.0}`, true},
+		{`nested == {tags: ["a"]}`, true},
+		{`grid == [[1], [2]]`, true},
+		{`alice == paris`, true},
+		{`alice == {City: "Paris", Country: "FR"}`, false},
+		{`2 in ids`, true},
+		{`"alice" in scores`, true},
+		{`"bob" in scores`, false},
+	}
+
+	for _, tt := range tests {
+		result := New(tt.source).WithVariables(vars).Execute()
+		if len(result.Errors) > 0 {
+			t.Errorf("%s: unexpected errors: %v", tt.source, result.Errors)
+			continue
+		}
+		if result.Value != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.source, tt.expected, result.Value)
+		}
+	}
+}
+
+type Invoice struct {
+	Total    *big.Rat
+	Discount big.Rat
+	Label    string
+}
+
+func (inv *Invoice) SetTotal(total *big.Rat) {
+	inv.Total = total
+}
+
+// TestBindDecimals tests that host *big.Rat values and strings convert to and from decimals
+func TestBindDecimals(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{`typeOf(invoice.Total)`, "decimal"},
+		{`toString(invoice.Total * 3)`, "59.97"},
+		{`toString(invoice.Total - invoice.Discount)`, "17.99"},
+		{`toString(invoice.Total + rate)`, "20.19"},
+	}
+
+	for _, tt := range tests {
+		invoice := &Invoice{Total: big.NewRat(1999, 100)}
+		invoice.Discount.SetInt64(2)
+		result := New(tt.source).
+			Bind("invoice", invoice).
+			WithVariables(map[string]interface{}{"rate": 0.2}).
+			Execute()
+		if len(result.Errors) > 0 {
+			t.Errorf("%s: unexpected errors: %v", tt.source, result.Errors)
+			continue
+		}
+		if result.Value != tt.expected {
+			t.Errorf("%s: expected %q, got %v", tt.source, tt.expected, result.Value)
+		}
+	}
+
+	invoice := &Invoice{}
+	result := New(`
+		invoice.Label = 0.1d + 0.2d
+		invoice.SetTotal(decimal("10.10") * 3)
+		invoice.Total
+	`).Bind("invoice", invoice).Execute()
+	if len(result.Errors) > 0 {
+		t.Fatalf("Unexpected errors: %v", result.Errors)
+	}
+	if invoice.Label != "0.3" {
+		t.Errorf("Expected label 0.3, got %q", invoice.Label)
+	}
+	if invoice.Total == nil || invoice.Total.Cmp(big.NewRat(303, 10)) != 0 {
+		t.Errorf("Expected total 30.3, got %v", invoice.Total)
+	}
+	if result.Value != invoice.Total {
+		t.Errorf("Expected the bound *big.Rat back, got %v", result.Value)
+	}
+
+	result = New(`invoice.SetTotal("12.50")`).Bind("invoice", invoice).Execute()
+	if len(result.Errors) > 0 {
+		t.Fatalf("Unexpected errors: %v", result.Errors)
+	}
+	if invoice.Total.Cmp(big.NewRat(25, 2)) != 0 {
+		t.Errorf("Expected total 12.5, got %v", invoice.Total)
+	}
+}
+
+// TestBindIntegerPrecision tests that 64-bit host integers round-trip without loss
+func TestBindIntegerPrecision(t *testing.T) {
+	order := &Order{ID: 1<<62 + 1, Snowflake: 1<<64 - 1, Quantity: 3}
+
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{`order.ID`, int64(1<<62 + 1)},
+		{`order.ID + 2`, int64(1<<62 + 3)},
+		{`order.SameID(order.ID)`, true},
+		{`order.SameID(order.ID + 1)`, false},
+		{`order.Snowflake`, uint64(1<<64 - 1)},
+		{`order.SameSnowflake(order.Snowflake)`, true},
+		{`order.Quantity * 2`, int64(6)},
+		{`order.Quantity / 2`, 1.5},
+		{`"order " + order.ID`, "order 4611686018427387905"},
+		{`ids[0] == order.ID`, true},
+		// uint64 values beyond the int64 range compare exactly, not as floats
+		{`snowflakes[0] == snowflakes[1]`, false},
+		{`snowflakes[0] == order.Snowflake`, true},
+		{`snowflakes[1] in [order.Snowflake]`, false},
+		{`snowflakes[1] < snowflakes[0]`, true},
+		{`max(snowflakes[1], snowflakes[0]) == order.Snowflake`, true},
+	}
+
+	for _, tt := range tests {
+		result := New(tt.source).
+			Bind("order", order).
+			WithVariables(map[string]interface{}{
+				"ids":        []int64{1<<62 + 1},
+				"snowflakes": []uint64{1<<64 - 1, 1<<64 - 2},
+			}).
+			Execute()
+		if len(result.Errors) > 0 {
+			t.Errorf("%s: unexpected errors: %v", tt.source, result.Errors)
+			continue
+		}
+		if result.Value != tt.expected {
+			t.Errorf("%s: expected %v (%T), got %v (%T)", tt.source, tt.expected, tt.expected, result.Value, result.Value)
+		}
+	}
+
+	result := New(`order.ID = order.ID - 1`).Bind("order", order).Execute()
+	if len(result.Errors) > 0 {
+		t.Fatalf("Unexpected errors: %v", result.Errors)
+	}
+	if order.ID != 1<<62 {
+		t.Errorf("Expected ID %d, got %d", int64(1<<62), order.ID)
+	}
+}
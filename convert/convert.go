@@ -0,0 +1,154 @@
+// Package convert turns Go values handed over by the host into KodiScript
+// runtime values. The interpreter and the natives both read host values
+// through it, so an element of a bound slice is the same value whether a
+// script indexes the slice or passes it to a native.
+package convert
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+)
+
+// Integer returns v as an int64 when it holds a Go integer that fits.
+func Integer(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint:
+		if uint64(n) <= math.MaxInt64 {
+			return int64(n), true
+		}
+	case uint64:
+		if n <= math.MaxInt64 {
+			return int64(n), true
+		}
+	}
+	return 0, false
+}
+
+// BigInteger returns v as a big.Int when it holds a Go integer of any kind,
+// including a uint or uint64 that does not fit in an int64.
+func BigInteger(v interface{}) (*big.Int, bool) {
+	if n, ok := Integer(v); ok {
+		return big.NewInt(n), true
+	}
+	switch n := v.(type) {
+	case uint:
+		return new(big.Int).SetUint64(uint64(n)), true
+	case uint64:
+		return new(big.Int).SetUint64(n), true
+	}
+	return nil, false
+}
+
+// FromGo converts a Go value to a KodiScript value. Go integers become
+// int64 and float32 becomes float64; a uint or uint64 beyond the int64
+// range is kept as is so it converts back losslessly. A big.Rat becomes a
+// decimal, and nil pointers and unexported values become null.
+func FromGo(val reflect.Value) interface{} {
+	if !val.IsValid() {
+		return nil
+	}
+	if val.Kind() == reflect.Ptr && val.IsNil() {
+		return nil
+	}
+	if !val.CanInterface() {
+		return nil
+	}
+	return fromInterface(val.Interface())
+}
+
+// Normalize converts a value that may come from the host, as FromGo does.
+func Normalize(v interface{}) interface{} {
+	switch v.(type) {
+	case nil, int64, float64, string, bool, []interface{}, map[string]interface{}, *big.Rat:
+		return v
+	}
+	return FromGo(reflect.ValueOf(v))
+}
+
+func fromInterface(v interface{}) interface{} {
+	if f, ok := v.(float32); ok {
+		return float64(f)
+	}
+	if d, ok := v.(big.Rat); ok {
+		return new(big.Rat).Set(&d)
+	}
+	if i, ok := Integer(v); ok {
+		return i
+	}
+	return v
+}
+
+// Collection returns the Go slice, array or string-keyed map held by v,
+// dereferencing pointers.
+func Collection(v interface{}) (reflect.Value, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return reflect.Value{}, false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		return rv, true
+	case reflect.Map:
+		return rv, rv.Type().Key().Kind() == reflect.String
+	}
+	return reflect.Value{}, false
+}
+
+// Sequence is a lazy run of values, such as a range, that reads like an
+// array wherever an array is expected.
+type Sequence interface {
+	Len() int
+	At(idx int) interface{}
+}
+
+// MaxSequenceLength bounds how many values a Sequence expands to when it has
+// to be materialized as an array.
+const MaxSequenceLength = 1_000_000
+
+// ToArray returns the elements of a KodiScript array, of a Sequence no
+// longer than MaxSequenceLength or of a Go slice or array, converting Go
+// elements with FromGo.
+func ToArray(v interface{}) ([]interface{}, bool) {
+	if arr, ok := v.([]interface{}); ok {
+		return arr, true
+	}
+	if seq, ok := v.(Sequence); ok {
+		if seq.Len() > MaxSequenceLength {
+			return nil, false
+		}
+		arr := make([]interface{}, seq.Len())
+		for i := range arr {
+			arr[i] = seq.At(i)
+		}
+		return arr, true
+	}
+	rv, ok := Collection(v)
+	if !ok || rv.Kind() == reflect.Map {
+		return nil, false
+	}
+	arr := make([]interface{}, rv.Len())
+	for i := range arr {
+		arr[i] = FromGo(rv.Index(i))
+	}
+	return arr, true
+}
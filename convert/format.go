@@ -0,0 +1,89 @@
+package convert
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/issadicko/kodi-script-go/decimal"
+)
+
+// String formats a value the way print and toString show it. Arrays and
+// objects keep the [a b] and map[k:v] layout, with their elements formatted
+// by the same rules, so a nested decimal prints as 1.5 rather than 3/2.
+func String(v interface{}) string {
+	var sb strings.Builder
+	writeValue(&sb, v)
+	return sb.String()
+}
+
+func writeValue(sb *strings.Builder, v interface{}) {
+	switch val := v.(type) {
+	case string:
+		sb.WriteString(val)
+	case int64:
+		sb.WriteString(strconv.FormatInt(val, 10))
+	case float64:
+		// An integral float keeps a fraction digit so it reads apart from an integer
+		s := strconv.FormatFloat(val, 'f', -1, 64)
+		sb.WriteString(s)
+		if !math.IsInf(val, 0) && !math.IsNaN(val) && !strings.Contains(s, ".") {
+			sb.WriteString(".0")
+		}
+	case *big.Rat:
+		sb.WriteString(decimal.Format(val))
+	case bool:
+		sb.WriteString(strconv.FormatBool(val))
+	case nil:
+		sb.WriteString("null")
+	case []interface{}:
+		sb.WriteByte('[')
+		for i, elem := range val {
+			if i > 0 {
+				sb.WriteByte(' ')
+			}
+			writeValue(sb, elem)
+		}
+		sb.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		sb.WriteString("map[")
+		for i, key := range keys {
+			if i > 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(key)
+			sb.WriteByte(':')
+			writeValue(sb, val[key])
+		}
+		sb.WriteByte(']')
+	default:
+		// Host collections and numbers print like the script values they convert to
+		if rv, ok := Collection(v); ok {
+			if rv.Kind() != reflect.Map {
+				arr, _ := ToArray(v)
+				writeValue(sb, arr)
+				return
+			}
+			obj := make(map[string]interface{}, rv.Len())
+			for _, key := range rv.MapKeys() {
+				obj[key.String()] = FromGo(rv.MapIndex(key))
+			}
+			writeValue(sb, obj)
+			return
+		}
+		if n := Normalize(v); reflect.TypeOf(n) != reflect.TypeOf(v) {
+			writeValue(sb, n)
+			return
+		}
+		sb.WriteString(fmt.Sprintf("%v", v))
+	}
+}
@@ -0,0 +1,97 @@
+// Package decimal holds the conversion and display rules of KodiScript
+// decimals, shared by the interpreter and the natives.
+//
+// Decimals are exact rationals (*big.Rat) at runtime, written 19.99d or built
+// with decimal("19.99"). A float is converted from its shortest decimal
+// representation, so 0.1 becomes exactly 1/10. Decimal values are never
+// mutated: every operation allocates its result.
+package decimal
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DisplayPlaces is the number of fraction digits shown for a decimal whose
+// expansion does not terminate, such as 1/3.
+const DisplayPlaces = 20
+
+// From returns v as a decimal when it holds a decimal or a finite number.
+func From(v interface{}) (*big.Rat, bool) {
+	switch n := v.(type) {
+	case *big.Rat:
+		return n, true
+	case float64:
+		return FromFloat(n)
+	case float32:
+		return FromFloat(float64(n))
+	case int:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case int8:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case int16:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case int32:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case int64:
+		return new(big.Rat).SetInt64(n), true
+	case uint:
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(uint64(n))), true
+	case uint8:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case uint16:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case uint32:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case uint64:
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(n)), true
+	}
+	return nil, false
+}
+
+// FromFloat converts f through its shortest decimal representation.
+func FromFloat(f float64) (*big.Rat, bool) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, false
+	}
+	return new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+// Format writes a decimal in plain notation: exactly when its expansion
+// terminates, otherwise rounded to DisplayPlaces digits.
+func Format(r *big.Rat) string {
+	if places, ok := Places(r); ok {
+		return r.FloatString(places)
+	}
+	s := strings.TrimRight(r.FloatString(DisplayPlaces), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// Places returns the number of fraction digits of r when its decimal
+// expansion terminates, that is when its denominator only has 2 and 5 as
+// prime factors.
+func Places(r *big.Rat) (int, bool) {
+	denom := new(big.Int).Set(r.Denom())
+	twos := int(denom.TrailingZeroBits())
+	denom.Rsh(denom, uint(twos))
+	fives := 0
+	five := big.NewInt(5)
+	quo, rem := new(big.Int), new(big.Int)
+	for {
+		quo.QuoRem(denom, five, rem)
+		if rem.Sign() != 0 {
+			break
+		}
+		denom.Set(quo)
+		fives++
+	}
+	if !denom.IsInt64() || denom.Int64() != 1 {
+		return 0, false
+	}
+	if twos > fives {
+		return twos, true
+	}
+	return fives, true
+}
@@ -0,0 +1,70 @@
+package interpreter
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/issadicko/kodi-script-go/decimal"
+)
+
+// Decimals are exact rationals (*big.Rat) at runtime; the decimal package
+// holds their conversion and display rules. An operation mixing a decimal
+// with an integer or a float is done in decimal arithmetic.
+
+// decimalOperands returns both operands as decimals when at least one of
+// them is a decimal and the other is a number.
+func decimalOperands(left, right Value) (*big.Rat, *big.Rat, bool) {
+	_, lok := left.(*big.Rat)
+	_, rok := right.(*big.Rat)
+	if !lok && !rok {
+		return nil, nil, false
+	}
+	l, ok := decimal.From(left)
+	if !ok {
+		return nil, nil, false
+	}
+	r, ok := decimal.From(right)
+	return l, r, ok
+}
+
+// evalDecimalArithmetic applies op to two decimals.
+func evalDecimalArithmetic(left, right *big.Rat, op string) (Value, error) {
+	switch op {
+	case "+":
+		return new(big.Rat).Add(left, right), nil
+	case "-":
+		return new(big.Rat).Sub(left, right), nil
+	case "*":
+		return new(big.Rat).Mul(left, right), nil
+	case "/":
+		if right.Sign() == 0 {
+			return nil, newRuntimeError(ErrorKindArithmetic, "division by zero")
+		}
+		return new(big.Rat).Quo(left, right), nil
+	case "%":
+		if right.Sign() == 0 {
+			return nil, newRuntimeError(ErrorKindArithmetic, "modulo by zero")
+		}
+		// Like math.Mod, the result has the sign of the dividend.
+		quo := new(big.Rat).Quo(left, right)
+		trunc := new(big.Int).Quo(quo.Num(), quo.Denom())
+		product := new(big.Rat).Mul(right, new(big.Rat).SetInt(trunc))
+		return product.Sub(left, product), nil
+	}
+	return nil, fmt.Errorf("unknown arithmetic operator: %s", op)
+}
+
+// compareDecimals returns the result of a comparison operator on two decimals.
+func compareDecimals(left, right *big.Rat, op string) bool {
+	cmp := left.Cmp(right)
+	switch op {
+	case "<":
+		return cmp < 0
+	case ">":
+		return cmp > 0
+	case "<=":
+		return cmp <= 0
+	default:
+		return cmp >= 0
+	}
+}
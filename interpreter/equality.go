@@ -0,0 +1,187 @@
+package interpreter
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/issadicko/kodi-script-go/convert"
+)
+
+// equalityVisit identifies a pair of Go containers being compared, so that
+// comparing cyclic values terminates.
+type equalityVisit struct {
+	a, b uintptr
+	typ  reflect.Type
+}
+
+// valuesEqual compares two runtime values structurally. Numbers of any type
+// compare by value, arrays element by element and objects key by key, Go
+// slices, maps and structs included: [1, 2] == [1.0, 2.0] and a bound
+// []int64 equals the array literal with the same numbers. It never panics,
+// even on cyclic values. Functions are only equal to themselves.
+func valuesEqual(a, b Value) bool {
+	return deepEqual(a, b, nil)
+}
+
+func deepEqual(a, b Value, visited map[equalityVisit]bool) bool {
+	if ad, bd, ok := decimalOperands(a, b); ok {
+		return ad.Cmp(bd) == 0
+	}
+	if ai, ok := convert.Integer(a); ok {
+		if bi, ok := convert.Integer(b); ok {
+			return ai == bi
+		}
+	}
+	// A host uint64 beyond the int64 range is compared exactly, not as a float
+	if ai, ok := convert.BigInteger(a); ok {
+		if bi, ok := convert.BigInteger(b); ok {
+			return ai.Cmp(bi) == 0
+		}
+	}
+	if an, ok := toNumber(a); ok {
+		bn, ok := toNumber(b)
+		return ok && an == bn
+	}
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	switch av := a.(type) {
+	case string:
+		bv, ok := b.(string)
+		return ok && av == bv
+	case bool:
+		bv, ok := b.(bool)
+		return ok && av == bv
+	case *Function:
+		return a == b
+	}
+
+	ac, aok := convert.Collection(a)
+	bc, bok := convert.Collection(b)
+	if aok || bok {
+		return aok && bok && collectionsEqual(ac, bc, visited)
+	}
+
+	if as, bs, ok := hostStructs(a, b); ok {
+		return structsEqual(as, bs, visited)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// collectionsEqual compares two slices or arrays, or two string-keyed maps,
+// converting their elements the way scripts read them.
+func collectionsEqual(a, b reflect.Value, visited map[equalityVisit]bool) bool {
+	aIsMap := a.Kind() == reflect.Map
+	if aIsMap != (b.Kind() == reflect.Map) || a.Len() != b.Len() {
+		return false
+	}
+	if markVisited(a, b, &visited) {
+		return true
+	}
+
+	if aIsMap {
+		for _, key := range a.MapKeys() {
+			other, ok := mapIndex(b, key.String())
+			if !ok || !deepEqual(convert.FromGo(a.MapIndex(key)), other, visited) {
+				return false
+			}
+		}
+		return true
+	}
+	for idx := 0; idx < a.Len(); idx++ {
+		if !deepEqual(convert.FromGo(a.Index(idx)), convert.FromGo(b.Index(idx)), visited) {
+			return false
+		}
+	}
+	return true
+}
+
+// hostStructs returns the Go structs held by a and b, dereferencing
+// pointers, when both are of the same struct type with only exported fields.
+// Other structs are left to reflect.DeepEqual.
+func hostStructs(a, b Value) (reflect.Value, reflect.Value, bool) {
+	as, bs := derefValue(reflect.ValueOf(a)), derefValue(reflect.ValueOf(b))
+	if as.Kind() != reflect.Struct || as.Type() != bs.Type() {
+		return reflect.Value{}, reflect.Value{}, false
+	}
+	for idx := 0; idx < as.NumField(); idx++ {
+		if !as.Type().Field(idx).IsExported() {
+			return reflect.Value{}, reflect.Value{}, false
+		}
+	}
+	return as, bs, true
+}
+
+// derefValue follows pointers until a non-pointer or a nil pointer.
+func derefValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+// structsEqual compares the fields of two structs of the same type.
+func structsEqual(a, b reflect.Value, visited map[equalityVisit]bool) bool {
+	if a.CanAddr() && b.CanAddr() {
+		if markVisited(a.Addr(), b.Addr(), &visited) {
+			return true
+		}
+	}
+	for idx := 0; idx < a.NumField(); idx++ {
+		if !deepEqual(convert.FromGo(a.Field(idx)), convert.FromGo(b.Field(idx)), visited) {
+			return false
+		}
+	}
+	return true
+}
+
+// markVisited records the pair of containers a and b and reports whether it
+// was already being compared. Go arrays are not tracked: held by value, they
+// cannot be part of a cycle.
+func markVisited(a, b reflect.Value, visited *map[equalityVisit]bool) bool {
+	switch a.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Ptr:
+	default:
+		return false
+	}
+	if *visited == nil {
+		*visited = make(map[equalityVisit]bool)
+	}
+	v := equalityVisit{a: a.Pointer(), b: b.Pointer(), typ: a.Type()}
+	if (*visited)[v] {
+		return true
+	}
+	(*visited)[v] = true
+	return false
+}
+
+// evalIn implements x in collection: membership for arrays (compared with
+// valuesEqual) and ranges, key presence for objects. Nothing is in null.
+func evalIn(item, collection Value) (Value, error) {
+	if collection == nil {
+		return false, nil
+	}
+	if rng, ok := collection.(Range); ok {
+		return rng.Contains(item), nil
+	}
+
+	rv, ok := convert.Collection(collection)
+	if !ok {
+		return nil, fmt.Errorf("cannot use 'in' with %T", collection)
+	}
+	if rv.Kind() == reflect.Map {
+		key, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("object keys are strings, got %T on the left of 'in'", item)
+		}
+		_, found := mapIndex(rv, key)
+		return found, nil
+	}
+	for idx := 0; idx < rv.Len(); idx++ {
+		if valuesEqual(item, convert.FromGo(rv.Index(idx))) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,98 @@
+package interpreter
+
+import (
+	"errors"
+
+	"github.com/issadicko/kodi-script-go/token"
+)
+
+// Error kinds exposed to scripts through the kind field of a caught error.
+const (
+	ErrorKindThrow      = "Error"           // value thrown by a throw statement
+	ErrorKindRuntime    = "RuntimeError"    // any other runtime failure
+	ErrorKindReference  = "ReferenceError"  // undefined variable
+	ErrorKindType       = "TypeError"       // operation on a value of the wrong type
+	ErrorKindArithmetic = "ArithmeticError" // division or modulo by zero
+	ErrorKindNative     = "NativeError"     // error returned by a native or host function
+)
+
+// RuntimeError is a script-level error that can be caught with try/catch.
+// Errors returned by Eval that are not caught are RuntimeErrors as well,
+// except for ErrMaxOperationsExceeded and ErrTimeout which cannot be caught.
+type RuntimeError struct {
+	Kind    string
+	Message string
+	Value   Value // the thrown value, nil for runtime errors
+	Line    int
+	Column  int
+	Cause   error // the underlying Go error, if any
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying Go error.
+func (e *RuntimeError) Unwrap() error {
+	return e.Cause
+}
+
+// Object returns the value bound to the catch variable.
+func (e *RuntimeError) Object() map[string]interface{} {
+	return map[string]interface{}{
+		"message": e.Message,
+		"kind":    e.Kind,
+		"line":    int64(e.Line),
+		"column":  int64(e.Column),
+		"value":   e.Value,
+	}
+}
+
+// newRuntimeError creates a RuntimeError of the given kind.
+func newRuntimeError(kind, message string) *RuntimeError {
+	return &RuntimeError{Kind: kind, Message: message}
+}
+
+// wrapError converts a Go error into a RuntimeError positioned at tok.
+// RuntimeErrors without a position get tok's position; execution limit
+// errors are returned unchanged so they can never be caught.
+func wrapError(err error, tok token.Token, kind string) error {
+	if err == nil || !isCatchable(err) {
+		return err
+	}
+	var re *RuntimeError
+	if errors.As(err, &re) {
+		if re.Line == 0 {
+			re.Line, re.Column = tok.Line, tok.Column
+		}
+		return re
+	}
+	return &RuntimeError{Kind: kind, Message: err.Error(), Line: tok.Line, Column: tok.Column, Cause: err}
+}
+
+// isCatchable reports whether an error may be handled by a catch block.
+// Control flow leaving a match arm is not an error and is never caught.
+func isCatchable(err error) bool {
+	var flow *controlFlow
+	return !errors.Is(err, ErrMaxOperationsExceeded) && !errors.Is(err, ErrTimeout) && !errors.As(err, &flow)
+}
+
+// thrownError builds the RuntimeError raised by a throw statement.
+// Thrown objects may provide their own message and kind fields.
+func thrownError(val Value, tok token.Token) *RuntimeError {
+	err := &RuntimeError{Kind: ErrorKindThrow, Value: val, Line: tok.Line, Column: tok.Column}
+	switch v := val.(type) {
+	case map[string]interface{}:
+		if msg, ok := v["message"].(string); ok {
+			err.Message = msg
+		} else {
+			err.Message = toString(val)
+		}
+		if kind, ok := v["kind"].(string); ok && kind != "" {
+			err.Kind = kind
+		}
+	default:
+		err.Message = toString(val)
+	}
+	return err
+}
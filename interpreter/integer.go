@@ -0,0 +1,227 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+
+	"github.com/issadicko/kodi-script-go/convert"
+)
+
+// maxDecimalExponent bounds the exponent of decimal ** integer, whose exact
+// result grows with the exponent.
+const maxDecimalExponent = 10000
+
+// Integers are int64 at runtime. Integer literals and Go integers from the
+// host stay exact; an operation whose result does not fit in an int64, or a
+// division that leaves a remainder, produces a float64 instead.
+
+// evalIntegerArithmetic applies op to two integers.
+func evalIntegerArithmetic(left, right int64, op string) (Value, error) {
+	switch op {
+	case "+":
+		sum := left + right
+		if (right > 0 && sum < left) || (right < 0 && sum > left) {
+			return float64(left) + float64(right), nil
+		}
+		return sum, nil
+	case "-":
+		diff := left - right
+		if (right > 0 && diff > left) || (right < 0 && diff < left) {
+			return float64(left) - float64(right), nil
+		}
+		return diff, nil
+	case "*":
+		if product, ok := multiplyIntegers(left, right); ok {
+			return product, nil
+		}
+		return float64(left) * float64(right), nil
+	case "/":
+		if right == 0 {
+			return nil, newRuntimeError(ErrorKindArithmetic, "division by zero")
+		}
+		if left%right == 0 && !(left == math.MinInt64 && right == -1) {
+			return left / right, nil
+		}
+		return float64(left) / float64(right), nil
+	case "%":
+		if right == 0 {
+			return nil, newRuntimeError(ErrorKindArithmetic, "modulo by zero")
+		}
+		if right == -1 {
+			return int64(0), nil
+		}
+		return left % right, nil
+	}
+	return nil, fmt.Errorf("unknown arithmetic operator: %s", op)
+}
+
+// multiplyIntegers returns left*right and whether it fits in an int64.
+func multiplyIntegers(left, right int64) (int64, bool) {
+	negative := (left < 0) != (right < 0)
+	hi, lo := bits.Mul64(absInteger(left), absInteger(right))
+	if hi != 0 {
+		return 0, false
+	}
+	if negative {
+		if lo > 1<<63 {
+			return 0, false
+		}
+		return -int64(lo), true
+	}
+	if lo > math.MaxInt64 {
+		return 0, false
+	}
+	return int64(lo), true
+}
+
+// absInteger returns |n| as a uint64, which also holds |math.MinInt64|.
+func absInteger(n int64) uint64 {
+	if n < 0 {
+		return uint64(-n)
+	}
+	return uint64(n)
+}
+
+// compareIntegers returns the result of a comparison operator on two integers.
+func compareIntegers(left, right int64, op string) bool {
+	switch op {
+	case "<":
+		return left < right
+	case ">":
+		return left > right
+	case "<=":
+		return left <= right
+	default:
+		return left >= right
+	}
+}
+
+// integralOperand returns val as an int64 for the bitwise operators, which
+// accept integers and floats or decimals with an integral value.
+func integralOperand(val Value) (int64, bool) {
+	if n, ok := convert.Integer(val); ok {
+		return n, true
+	}
+	switch v := val.(type) {
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			return int64(v), true
+		}
+	case *big.Rat:
+		if v.IsInt() && v.Num().IsInt64() {
+			return v.Num().Int64(), true
+		}
+	}
+	return 0, false
+}
+
+// integerOperandError reports a non-integral operand of a bitwise operator.
+func integerOperandError(op string, val Value) error {
+	if _, ok := toNumber(val); ok {
+		return fmt.Errorf("operator %s requires integer operands, got %s", op, toString(val))
+	}
+	return fmt.Errorf("operator %s requires integer operands, got %T", op, val)
+}
+
+// evalBitwise applies & | ^ << >> to two integral operands. Shifts are
+// arithmetic: >> keeps the sign. A shift count outside 0..63 raises an
+// ArithmeticError rather than silently shifting every bit out.
+func evalBitwise(left, right Value, op string) (Value, error) {
+	l, ok := integralOperand(left)
+	if !ok {
+		return nil, integerOperandError(op, left)
+	}
+	r, ok := integralOperand(right)
+	if !ok {
+		return nil, integerOperandError(op, right)
+	}
+
+	switch op {
+	case "&":
+		return l & r, nil
+	case "|":
+		return l | r, nil
+	case "^":
+		return l ^ r, nil
+	case "<<", ">>":
+		if r < 0 {
+			return nil, newRuntimeError(ErrorKindArithmetic, "negative shift count")
+		}
+		if r >= 64 {
+			return nil, newRuntimeError(ErrorKindArithmetic, fmt.Sprintf("shift count %d out of range", r))
+		}
+		if op == "<<" {
+			return l << uint64(r), nil
+		}
+		return l >> uint64(r), nil
+	}
+	return nil, fmt.Errorf("unknown bitwise operator: %s", op)
+}
+
+// evalPower applies **. An integer raised to a non-negative integer stays
+// exact unless it overflows, a decimal raised to an integer stays a decimal,
+// and anything else is computed with math.Pow. Zero raised to a negative
+// power is a division by zero, as with /.
+func evalPower(left, right Value) (Value, error) {
+	if d, ok := left.(*big.Rat); ok {
+		if exp, ok := integralOperand(right); ok {
+			return decimalPower(d, exp)
+		}
+	}
+	if base, ok := convert.Integer(left); ok {
+		if exp, ok := convert.Integer(right); ok && exp >= 0 {
+			if n, ok := integerPower(base, exp); ok {
+				return n, nil
+			}
+		}
+	}
+	base, lok := toNumber(left)
+	exp, rok := toNumber(right)
+	if !lok || !rok {
+		return nil, fmt.Errorf("cannot perform ** on %T and %T", left, right)
+	}
+	if base == 0 && exp < 0 {
+		return nil, newRuntimeError(ErrorKindArithmetic, "division by zero")
+	}
+	return math.Pow(base, exp), nil
+}
+
+// integerPower returns base**exp and whether it fits in an int64.
+func integerPower(base, exp int64) (int64, bool) {
+	result := int64(1)
+	for exp > 0 {
+		var ok bool
+		if exp&1 == 1 {
+			if result, ok = multiplyIntegers(result, base); !ok {
+				return 0, false
+			}
+		}
+		exp >>= 1
+		if exp > 0 {
+			if base, ok = multiplyIntegers(base, base); !ok {
+				return 0, false
+			}
+		}
+	}
+	return result, true
+}
+
+// decimalPower returns d**exp exactly.
+func decimalPower(d *big.Rat, exp int64) (Value, error) {
+	if exp > maxDecimalExponent || exp < -maxDecimalExponent {
+		return nil, newRuntimeError(ErrorKindArithmetic, fmt.Sprintf("decimal exponent %d out of range", exp))
+	}
+	if exp < 0 && d.Sign() == 0 {
+		return nil, newRuntimeError(ErrorKindArithmetic, "division by zero")
+	}
+	e := big.NewInt(exp)
+	e.Abs(e)
+	num := new(big.Int).Exp(d.Num(), e, nil)
+	denom := new(big.Int).Exp(d.Denom(), e, nil)
+	if exp < 0 {
+		num, denom = denom, num
+	}
+	return new(big.Rat).SetFrac(num, denom), nil
+}
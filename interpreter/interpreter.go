@@ -6,10 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"math"
-	"strconv"
+	"math/big"
+	"reflect"
+	"strings"
 	"sync"
 
 	"github.com/issadicko/kodi-script-go/ast"
+	"github.com/issadicko/kodi-script-go/convert"
 	"github.com/issadicko/kodi-script-go/natives"
 )
 
@@ -27,9 +30,36 @@ type ReturnValue struct {
 	Value Value
 }
 
+// BreakSignal signals a break out of the innermost loop, or of the loop
+// with the matching label when Label is set.
+type BreakSignal struct {
+	Label string
+}
+
+// ContinueSignal signals a jump to the next iteration of the innermost loop,
+// or of the loop with the matching label when Label is set.
+type ContinueSignal struct {
+	Label string
+}
+
+// controlFlow carries a return, break or continue out of a match arm
+// evaluated inside an expression. The statement holding the expression turns
+// it back into the signal, so 1 + match (x) { 1 -> { return 10 } } returns
+// from the function instead of adding a signal to 1.
+type controlFlow struct {
+	signal Value
+}
+
+func (c *controlFlow) Error() string {
+	return "control flow signal outside of a statement"
+}
+
 // Function represents a user-defined function.
 type Function struct {
+	Name       string // empty for anonymous functions
 	Parameters []*ast.Identifier
+	Defaults   []ast.Expression // default value per parameter, nil entries when absent
+	Rest       *ast.Identifier  // collects extra arguments, may be nil
 	Body       *ast.BlockStatement
 	Env        *Environment
 }
@@ -42,8 +72,10 @@ type NativeFunction struct {
 // Environment holds variable bindings.
 type Environment struct {
 	store  map[string]Value
+	consts map[string]bool // names bound as constants, nil until the first one
 	outer  *Environment
 	output []string // captured output from print()
+	block  bool     // block scope: undeclared assignments go to the enclosing function scope
 }
 
 // envPool pools Environment objects to reduce allocations.
@@ -69,6 +101,7 @@ func (e *Environment) Release() {
 	for k := range e.store {
 		delete(e.store, k)
 	}
+	e.consts = nil
 	e.output = e.output[:0]
 	e.outer = nil
 	envPool.Put(e)
@@ -84,6 +117,12 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 	}
 }
 
+// newBlockEnvironment creates the scope of a block or loop iteration.
+// Its store is allocated on the first declaration, since most blocks declare nothing.
+func newBlockEnvironment(outer *Environment) *Environment {
+	return &Environment{outer: outer, block: true}
+}
+
 // Get retrieves a variable value.
 func (e *Environment) Get(name string) (Value, bool) {
 	val, ok := e.store[name]
@@ -95,32 +134,91 @@ func (e *Environment) Get(name string) (Value, bool) {
 
 // Set sets a variable value.
 func (e *Environment) Set(name string, val Value) {
+	if e.store == nil {
+		e.store = make(map[string]Value, 4)
+	}
 	e.store[name] = val
 }
 
+// Assign updates the nearest existing binding of name. An undeclared name is
+// created in the innermost function or global scope, never in a block scope.
+func (e *Environment) Assign(name string, val Value) {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			env.store[name] = val
+			return
+		}
+	}
+	target := e
+	for target.block && target.outer != nil {
+		target = target.outer
+	}
+	target.Set(name, val)
+}
+
+// SetConst binds a constant that scripts cannot reassign.
+func (e *Environment) SetConst(name string, val Value) {
+	e.Set(name, val)
+	if e.consts == nil {
+		e.consts = make(map[string]bool)
+	}
+	e.consts[name] = true
+}
+
+// IsConst reports whether the nearest binding of name is a constant.
+func (e *Environment) IsConst(name string) bool {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			return env.consts[name]
+		}
+	}
+	return false
+}
+
+// isLocalConst reports whether name is a constant bound in this environment itself.
+func (e *Environment) isLocalConst(name string) bool {
+	return e.consts[name]
+}
+
 // GetOutput returns all captured output.
 func (e *Environment) GetOutput() []string {
 	return e.output
 }
 
-// AddOutput adds a line to captured output.
+// AddOutput adds a line to captured output. Output is always kept by the
+// root environment, so print() works the same inside functions and blocks.
 func (e *Environment) AddOutput(line string) {
-	e.output = append(e.output, line)
+	root := e
+	for root.outer != nil {
+		root = root.outer
+	}
+	root.output = append(root.output, line)
 }
 
 // Interpreter evaluates AST nodes.
 type Interpreter struct {
 	env     *Environment
+	globals *Environment // root environment of the main script, keeps print() output
 	natives *natives.Registry
 	opCount int64           // Current operation count
 	maxOps  int64           // Maximum allowed operations (0 = unlimited)
 	ctx     context.Context // Context for timeout support
+
+	strictArity   bool              // Reject calls with missing or extra arguments
+	legacyScoping bool              // Run blocks in the enclosing environment (pre block-scoping behavior)
+	collation     natives.Collation // Orders strings in comparisons, nil for byte order
+
+	moduleLoader ModuleLoader       // Loads imported modules, nil when imports are disabled
+	modules      map[string]*module // Modules evaluated during the current Eval
+	importStack  []string           // Modules being evaluated, to report import cycles
 }
 
 // New creates a new Interpreter.
 func New() *Interpreter {
+	env := NewEnvironment()
 	return &Interpreter{
-		env:     NewEnvironment(),
+		env:     env,
+		globals: env,
 		natives: natives.DefaultBuiltins, // Use shared builtins by default
 	}
 }
@@ -144,6 +242,19 @@ func NewWithEnv(variables map[string]interface{}) *Interpreter {
 func (i *Interpreter) Eval(program *ast.Program) (Value, error) {
 	var result Value
 
+	// Modules are evaluated once per execution
+	i.modules = nil
+	i.importStack = nil
+
+	// The script's own declarations live in a fresh scope over the host
+	// globals, so the same interpreter can evaluate a program again
+	i.env = NewEnclosedEnvironment(i.globals)
+	defer func() { i.env = i.globals }()
+
+	if err := i.hoistFunctions(program.Statements); err != nil {
+		return nil, err
+	}
+
 	for _, stmt := range program.Statements {
 		val, err := i.evalStatement(stmt)
 		if err != nil {
@@ -153,15 +264,72 @@ func (i *Interpreter) Eval(program *ast.Program) (Value, error) {
 		if rv, ok := val.(*ReturnValue); ok {
 			return rv.Value, nil
 		}
+		if err := loopSignalError(val); err != nil {
+			return nil, err
+		}
 		result = val
 	}
 
 	return result, nil
 }
 
+// loopSignalError reports a break or continue that escaped every enclosing loop.
+func loopSignalError(val Value) error {
+	switch sig := val.(type) {
+	case *BreakSignal:
+		if sig.Label != "" {
+			return fmt.Errorf("break: unknown loop label '%s'", sig.Label)
+		}
+		return fmt.Errorf("break outside of a loop")
+	case *ContinueSignal:
+		if sig.Label != "" {
+			return fmt.Errorf("continue: unknown loop label '%s'", sig.Label)
+		}
+		return fmt.Errorf("continue outside of a loop")
+	}
+	return nil
+}
+
+// hoistFunctions binds every function declared directly in stmts before any
+// of them runs, so functions can be called before their declaration and
+// can call each other recursively.
+func (i *Interpreter) hoistFunctions(stmts []ast.Statement) error {
+	for _, stmt := range stmts {
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			stmt = export.Declaration
+		}
+		if decl, ok := stmt.(*ast.FunctionDecl); ok {
+			if err := i.declare(decl.Name.Value, i.newFunction(decl.Function, decl.Name.Value), false); err != nil {
+				return wrapError(err, decl.Name.Token, ErrorKindType)
+			}
+		}
+	}
+	return nil
+}
+
+// newFunction creates a closure over the current environment.
+func (i *Interpreter) newFunction(lit *ast.FunctionLiteral, name string) *Function {
+	return &Function{
+		Name:       name,
+		Parameters: lit.Parameters,
+		Defaults:   lit.Defaults,
+		Rest:       lit.Rest,
+		Body:       lit.Body,
+		Env:        i.env,
+	}
+}
+
+// labelName returns the name of an optional loop label.
+func labelName(label *ast.Identifier) string {
+	if label == nil {
+		return ""
+	}
+	return label.Value
+}
+
 // GetOutput returns captured print() output.
 func (i *Interpreter) GetOutput() []string {
-	return i.env.GetOutput()
+	return i.globals.GetOutput()
 }
 
 // SetGlobal sets a global variable in the interpreter's environment.
@@ -169,6 +337,27 @@ func (i *Interpreter) SetGlobal(name string, value Value) {
 	i.env.Set(name, value)
 }
 
+// SetConstant sets a read-only global: scripts can read it but neither
+// reassign nor redeclare it.
+func (i *Interpreter) SetConstant(name string, value Value) {
+	i.env.SetConst(name, value)
+}
+
+// declare binds a new variable in the current environment, refusing to
+// replace a constant bound in the same environment. At the top level of the
+// script, host constants count as bound in the same environment.
+func (i *Interpreter) declare(name string, val Value, constant bool) error {
+	if i.env.isLocalConst(name) || i.env.outer == i.globals && i.globals.isLocalConst(name) {
+		return newRuntimeError(ErrorKindType, fmt.Sprintf("cannot redeclare constant '%s'", name))
+	}
+	if constant {
+		i.env.SetConst(name, val)
+	} else {
+		i.env.Set(name, val)
+	}
+	return nil
+}
+
 // SetMaxOperations sets the maximum number of operations allowed.
 // If maxOps is 0, there is no limit (default behavior).
 func (i *Interpreter) SetMaxOperations(maxOps int64) {
@@ -187,6 +376,29 @@ func (i *Interpreter) checkOperationLimit() error {
 	return nil
 }
 
+// SetStrictArity enables or disables strict arity checking. When enabled,
+// calling a script function with too few or too many arguments is a
+// TypeError reported at the call site. Callbacks invoked by map, filter,
+// reduce, find and findIndex are not checked.
+func (i *Interpreter) SetStrictArity(strict bool) {
+	i.strictArity = strict
+}
+
+// SetCollation sets how the comparison operators order strings. A nil
+// collation compares them byte by byte.
+func (i *Interpreter) SetCollation(collate natives.Collation) {
+	i.collation = collate
+}
+
+// SetLegacyScoping restores the scoping rules of earlier versions: blocks
+// and loop bodies share the enclosing environment, so their let declarations
+// and loop variables remain visible afterwards, and assigning to a variable
+// always binds it in the current function scope. Provided for scripts that
+// rely on the old behavior.
+func (i *Interpreter) SetLegacyScoping(legacy bool) {
+	i.legacyScoping = legacy
+}
+
 // SetContext sets a context for timeout support.
 func (i *Interpreter) SetContext(ctx context.Context) {
 	i.ctx = ctx
@@ -206,6 +418,17 @@ func (i *Interpreter) checkTimeout() error {
 }
 
 func (i *Interpreter) evalStatement(stmt ast.Statement) (Value, error) {
+	val, err := i.execStatement(stmt)
+	if err != nil {
+		var flow *controlFlow
+		if errors.As(err, &flow) {
+			return flow.signal, nil
+		}
+	}
+	return val, err
+}
+
+func (i *Interpreter) execStatement(stmt ast.Statement) (Value, error) {
 	// Check operation limit at each statement
 	if err := i.checkOperationLimit(); err != nil {
 		return nil, err
@@ -221,16 +444,22 @@ func (i *Interpreter) evalStatement(stmt ast.Statement) (Value, error) {
 		if err != nil {
 			return nil, err
 		}
-		i.env.Set(s.Name.Value, val)
+		if s.Pattern != nil {
+			if err := i.bindPattern(s.Pattern, val, s.Const); err != nil {
+				return nil, wrapError(err, s.Token, ErrorKindType)
+			}
+			return val, nil
+		}
+		if err := i.declare(s.Name.Value, val, s.Const); err != nil {
+			return nil, wrapError(err, s.Name.Token, ErrorKindType)
+		}
 		return val, nil
 
 	case *ast.Assignment:
-		val, err := i.evalExpression(s.Value)
-		if err != nil {
-			return nil, err
-		}
-		i.env.Set(s.Name.Value, val)
-		return val, nil
+		return i.evalAssignment(s)
+
+	case *ast.MemberAssignment:
+		return i.evalMemberAssignment(s)
 
 	case *ast.ExpressionStatement:
 		return i.evalExpression(s.Expression)
@@ -255,6 +484,32 @@ func (i *Interpreter) evalStatement(stmt ast.Statement) (Value, error) {
 	case *ast.WhileStatement:
 		return i.evalWhileStatement(s)
 
+	case *ast.BreakStatement:
+		return &BreakSignal{Label: labelName(s.Label)}, nil
+
+	case *ast.ContinueStatement:
+		return &ContinueSignal{Label: labelName(s.Label)}, nil
+
+	case *ast.TryStatement:
+		return i.evalTryStatement(s)
+
+	case *ast.ThrowStatement:
+		val, err := i.evalExpression(s.Value)
+		if err != nil {
+			return nil, err
+		}
+		return nil, thrownError(val, s.Token)
+
+	case *ast.FunctionDecl:
+		// Already bound when the enclosing block was hoisted
+		return nil, nil
+
+	case *ast.ImportStatement:
+		return nil, i.evalImportStatement(s)
+
+	case *ast.ExportStatement:
+		return i.evalStatement(s.Declaration)
+
 	default:
 		return nil, fmt.Errorf("unknown statement type: %T", stmt)
 	}
@@ -267,16 +522,16 @@ func (i *Interpreter) evalForStatement(stmt *ast.ForStatement) (Value, error) {
 		return nil, err
 	}
 
-	// Must be an array
-	arr, ok := iterableVal.([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("for-in requires an array, got %T", iterableVal)
+	iter, err := newForIterator(iterableVal)
+	if err != nil {
+		return nil, wrapError(err, stmt.Token, ErrorKindType)
 	}
 
 	var result Value
-	varName := stmt.Variable.Value
+	label := labelName(stmt.Label)
 
-	for _, item := range arr {
+loop:
+	for idx := 0; idx < iter.size; idx++ {
 		// Check operation limit at each iteration
 		if err := i.checkOperationLimit(); err != nil {
 			return nil, err
@@ -286,18 +541,30 @@ func (i *Interpreter) evalForStatement(stmt *ast.ForStatement) (Value, error) {
 			return nil, err
 		}
 
-		// Set loop variable in current environment
-		i.env.Set(varName, item)
-
 		// Execute body
-		val, err := i.evalBlockStatement(stmt.Body)
+		key, item := iter.entry(idx)
+		if stmt.Index == nil && iter.keyed {
+			item = key
+		}
+		val, err := i.evalForIteration(stmt, key, item)
 		if err != nil {
 			return nil, err
 		}
 
-		// Check for return
-		if rv, ok := val.(*ReturnValue); ok {
-			return rv, nil
+		// Check for return, break and continue
+		switch sig := val.(type) {
+		case *ReturnValue:
+			return sig, nil
+		case *BreakSignal:
+			if sig.Label != "" && sig.Label != label {
+				return sig, nil
+			}
+			break loop
+		case *ContinueSignal:
+			if sig.Label != "" && sig.Label != label {
+				return sig, nil
+			}
+			continue loop
 		}
 
 		result = val
@@ -306,9 +573,38 @@ func (i *Interpreter) evalForStatement(stmt *ast.ForStatement) (Value, error) {
 	return result, nil
 }
 
+// evalForIteration binds the loop variables and runs the body once. Each
+// iteration gets a fresh scope, so closures created in the body capture
+// that iteration's value.
+func (i *Interpreter) evalForIteration(stmt *ast.ForStatement, key, item Value) (Value, error) {
+	savedEnv := i.env
+	if !i.legacyScoping {
+		i.env = newBlockEnvironment(i.env)
+	}
+	defer func() { i.env = savedEnv }()
+
+	if stmt.Index != nil {
+		if err := i.declare(stmt.Index.Value, key, false); err != nil {
+			return nil, wrapError(err, stmt.Index.Token, ErrorKindType)
+		}
+	}
+
+	if stmt.Pattern != nil {
+		if err := i.bindPattern(stmt.Pattern, item, false); err != nil {
+			return nil, wrapError(err, stmt.Token, ErrorKindType)
+		}
+	} else if err := i.declare(stmt.Variable.Value, item, false); err != nil {
+		return nil, wrapError(err, stmt.Variable.Token, ErrorKindType)
+	}
+
+	return i.evalBlockStatement(stmt.Body)
+}
+
 func (i *Interpreter) evalWhileStatement(stmt *ast.WhileStatement) (Value, error) {
 	var result Value
+	label := labelName(stmt.Label)
 
+loop:
 	for {
 		// Check operation limit at each iteration
 		if err := i.checkOperationLimit(); err != nil {
@@ -331,14 +627,25 @@ func (i *Interpreter) evalWhileStatement(stmt *ast.WhileStatement) (Value, error
 		}
 
 		// Execute body
-		val, err := i.evalBlockStatement(stmt.Body)
+		val, err := i.evalScopedBlock(stmt.Body)
 		if err != nil {
 			return nil, err
 		}
 
-		// Check for return
-		if rv, ok := val.(*ReturnValue); ok {
-			return rv, nil
+		// Check for return, break and continue
+		switch sig := val.(type) {
+		case *ReturnValue:
+			return sig, nil
+		case *BreakSignal:
+			if sig.Label != "" && sig.Label != label {
+				return sig, nil
+			}
+			break loop
+		case *ContinueSignal:
+			if sig.Label != "" && sig.Label != label {
+				return sig, nil
+			}
+			continue loop
 		}
 
 		result = val
@@ -354,24 +661,220 @@ func (i *Interpreter) evalIfStatement(stmt *ast.IfStatement) (Value, error) {
 	}
 
 	if isTruthy(condition) {
-		return i.evalBlockStatement(stmt.Consequence)
+		return i.evalScopedBlock(stmt.Consequence)
 	} else if stmt.Alternative != nil {
-		return i.evalBlockStatement(stmt.Alternative)
+		return i.evalScopedBlock(stmt.Alternative)
 	}
 
 	return nil, nil
 }
 
+// bindPattern binds a value to an identifier or destructures it through an
+// object or array pattern. Object patterns read map keys and exported struct
+// fields; defaults apply when a value is missing or null.
+func (i *Interpreter) bindPattern(target ast.Expression, val Value, constant bool) error {
+	switch t := target.(type) {
+	case *ast.Identifier:
+		return i.declare(t.Value, val, constant)
+
+	case *ast.ObjectPattern:
+		if val == nil {
+			return fmt.Errorf("cannot destructure null as an object")
+		}
+		for _, prop := range t.Properties {
+			field, _ := lookupField(val, prop.Key)
+			if err := i.bindPatternValue(prop.Target, prop.Default, field, constant); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *ast.ArrayPattern:
+		arr, ok := convert.ToArray(val)
+		if !ok {
+			return fmt.Errorf("cannot destructure %T as an array", val)
+		}
+		for idx, elem := range t.Elements {
+			var item Value
+			if idx < len(arr) {
+				item = arr[idx]
+			}
+			if err := i.bindPatternValue(elem.Target, elem.Default, item, constant); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("invalid binding target: %T", target)
+	}
+}
+
+// bindPatternValue binds one pattern entry, evaluating its default when val is null.
+func (i *Interpreter) bindPatternValue(target, defaultExpr ast.Expression, val Value, constant bool) error {
+	if val == nil && defaultExpr != nil {
+		var err error
+		val, err = i.evalExpression(defaultExpr)
+		if err != nil {
+			return err
+		}
+	}
+	return i.bindPattern(target, val, constant)
+}
+
+// evalTryStatement runs the try block, hands catchable errors to the catch block
+// and always runs the finally block unless an execution limit was hit.
+func (i *Interpreter) evalTryStatement(stmt *ast.TryStatement) (Value, error) {
+	val, err := i.evalScopedBlock(stmt.Block)
+
+	if err != nil && stmt.Handler != nil && isCatchable(err) {
+		val, err = i.evalCatchClause(stmt, err)
+	}
+
+	if stmt.Finalizer != nil && (err == nil || isCatchable(err)) {
+		finalVal, finalErr := i.evalScopedBlock(stmt.Finalizer)
+		if finalErr != nil {
+			return nil, finalErr
+		}
+		// Control flow in finally overrides the outcome of try/catch
+		switch finalVal.(type) {
+		case *ReturnValue, *BreakSignal, *ContinueSignal:
+			return finalVal, nil
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+// evalCatchClause runs the catch block of stmt with the caught error bound to
+// its parameter, scoped to the catch block.
+func (i *Interpreter) evalCatchClause(stmt *ast.TryStatement, err error) (Value, error) {
+	var caught *RuntimeError
+	errors.As(wrapError(err, stmt.Token, ErrorKindRuntime), &caught)
+
+	savedEnv := i.env
+	if !i.legacyScoping {
+		i.env = newBlockEnvironment(i.env)
+	}
+	defer func() { i.env = savedEnv }()
+
+	if stmt.Param != nil {
+		if err := i.declare(stmt.Param.Value, caught.Object(), false); err != nil {
+			return nil, err
+		}
+	}
+	return i.evalBlockStatement(stmt.Handler)
+}
+
+// evalMatchExpr evaluates the body of the first arm whose pattern matches the subject.
+// Returns null when no arm matches and there is no else arm.
+func (i *Interpreter) evalMatchExpr(expr *ast.MatchExpr) (Value, error) {
+	subject, err := i.evalExpression(expr.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, arm := range expr.Arms {
+		matched := arm.Patterns == nil // else arm
+		for _, pattern := range arm.Patterns {
+			matched, err = i.matchPattern(subject, pattern)
+			if err != nil {
+				return nil, err
+			}
+			if matched {
+				break
+			}
+		}
+		if matched {
+			val, err := i.evalScopedBlock(arm.Body)
+			if err != nil {
+				return nil, err
+			}
+			switch val.(type) {
+			case *ReturnValue, *BreakSignal, *ContinueSignal:
+				return nil, &controlFlow{signal: val}
+			}
+			return val, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// matchPattern reports whether a value matches a match-arm pattern.
+// Supported patterns: the wildcard _, ranges, object shapes and plain values.
+func (i *Interpreter) matchPattern(subject Value, pattern ast.Expression) (bool, error) {
+	switch p := pattern.(type) {
+	case *ast.Identifier:
+		if p.Value == "_" {
+			return true, nil
+		}
+
+	case *ast.ObjectLiteral:
+		// Object shape: every key must be present and its value must match the nested pattern
+		for _, entry := range p.Entries {
+			if spread, ok := entry.Value.(*ast.SpreadExpr); ok {
+				return false, wrapError(fmt.Errorf("cannot spread into an object pattern"), spread.Token, ErrorKindType)
+			}
+			field, ok := lookupField(subject, entry.Key)
+			if !ok {
+				return false, nil
+			}
+			matched, err := i.matchPattern(field, entry.Value)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+
+	val, err := i.evalExpression(pattern)
+	if err != nil {
+		return false, err
+	}
+	// A range pattern matches the numbers between its bounds
+	if rng, ok := val.(Range); ok {
+		return rng.Contains(subject), nil
+	}
+	return valuesEqual(subject, val), nil
+}
+
+// evalScopedBlock evaluates a block in its own scope, so that its
+// declarations are not visible once it completes.
+func (i *Interpreter) evalScopedBlock(block *ast.BlockStatement) (Value, error) {
+	if i.legacyScoping {
+		return i.evalBlockStatement(block)
+	}
+	return i.evalBlockIn(block, newBlockEnvironment(i.env))
+}
+
+// evalBlockIn evaluates a block with env as the current environment.
+func (i *Interpreter) evalBlockIn(block *ast.BlockStatement, env *Environment) (Value, error) {
+	savedEnv := i.env
+	i.env = env
+	val, err := i.evalBlockStatement(block)
+	i.env = savedEnv
+	return val, err
+}
+
 func (i *Interpreter) evalBlockStatement(block *ast.BlockStatement) (Value, error) {
 	var result Value
 
+	if err := i.hoistFunctions(block.Statements); err != nil {
+		return nil, err
+	}
+
 	for _, stmt := range block.Statements {
 		val, err := i.evalStatement(stmt)
 		if err != nil {
 			return nil, err
 		}
-		// Propagate return values up the call stack
-		if _, ok := val.(*ReturnValue); ok {
+		// Propagate return values and loop signals up the call stack
+		switch val.(type) {
+		case *ReturnValue, *BreakSignal, *ContinueSignal:
 			return val, nil
 		}
 		result = val
@@ -403,6 +906,12 @@ func (i *Interpreter) evalExpression(expr ast.Expression) (Value, error) {
 	case *ast.NumberLiteral:
 		return e.Value, nil
 
+	case *ast.IntegerLiteral:
+		return e.Value, nil
+
+	case *ast.DecimalLiteral:
+		return e.Value, nil
+
 	case *ast.StringLiteral:
 		return e.Value, nil
 
@@ -423,10 +932,10 @@ func (i *Interpreter) evalExpression(expr ast.Expression) (Value, error) {
 		if fn := i.natives.Get(e.Value); fn != nil {
 			return &NativeFunction{Fn: fn}, nil
 		}
-		return nil, fmt.Errorf("undefined variable: %s", e.Value)
+		return nil, wrapError(fmt.Errorf("undefined variable: %s", e.Value), e.Token, ErrorKindReference)
 
 	case *ast.FunctionLiteral:
-		return &Function{Parameters: e.Parameters, Body: e.Body, Env: i.env}, nil
+		return i.newFunction(e, ""), nil
 
 	case *ast.BinaryExpr:
 		return i.evalBinaryExpr(e)
@@ -435,49 +944,53 @@ func (i *Interpreter) evalExpression(expr ast.Expression) (Value, error) {
 		return i.evalUnaryExpr(e)
 
 	case *ast.ArrayLiteral:
-		elements := make([]interface{}, len(e.Elements))
-		for idx, el := range e.Elements {
-			val, err := i.evalExpression(el)
-			if err != nil {
-				return nil, err
-			}
+		values, err := i.evalExpressionList(e.Elements)
+		if err != nil {
+			return nil, err
+		}
+		elements := make([]interface{}, len(values))
+		for idx, val := range values {
 			elements[idx] = val
 		}
 		return elements, nil
 
 	case *ast.ObjectLiteral:
-		pairs := make(map[string]interface{})
-		for key, valExpr := range e.Pairs {
-			val, err := i.evalExpression(valExpr)
-			if err != nil {
-				return nil, err
-			}
-			pairs[key] = val
-		}
-		return pairs, nil
+		return i.evalObjectLiteral(e)
 
-	case *ast.IndexExpr:
-		left, err := i.evalExpression(e.Left)
+	case *ast.IndexExpr, *ast.SafeAccessExpr, *ast.PropertyAccessExpr, *ast.CallExpr:
+		val, _, err := i.evalChainLink(e)
+		return val, err
+
+	case *ast.ElvisExpr:
+		return i.evalElvisExpr(e)
+
+	case *ast.RangeExpr:
+		start, err := i.evalExpression(e.Start)
 		if err != nil {
 			return nil, err
 		}
-		index, err := i.evalExpression(e.Index)
+		end, err := i.evalExpression(e.End)
 		if err != nil {
 			return nil, err
 		}
-		return i.evalIndexExpression(left, index)
-
-	case *ast.SafeAccessExpr:
-		return i.evalSafeAccess(e)
-
-	case *ast.ElvisExpr:
-		return i.evalElvisExpr(e)
+		rng, err := newRange(start, end, e.Inclusive)
+		if err != nil {
+			return nil, wrapError(err, e.Token, ErrorKindType)
+		}
+		return rng, nil
 
-	case *ast.PropertyAccessExpr:
-		return i.evalPropertyAccess(e)
+	case *ast.ConditionalExpr:
+		condition, err := i.evalExpression(e.Condition)
+		if err != nil {
+			return nil, err
+		}
+		if isTruthy(condition) {
+			return i.evalExpression(e.Consequence)
+		}
+		return i.evalExpression(e.Alternative)
 
-	case *ast.CallExpr:
-		return i.evalCallExpr(e)
+	case *ast.MatchExpr:
+		return i.evalMatchExpr(e)
 
 	default:
 		return nil, fmt.Errorf("unknown expression type: %T", expr)
@@ -518,7 +1031,16 @@ func (i *Interpreter) evalBinaryExpr(expr *ast.BinaryExpr) (Value, error) {
 		return nil, err
 	}
 
-	switch expr.Operator {
+	val, err := i.evalBinaryOperator(expr.Operator, left, right)
+	if err != nil {
+		return nil, wrapError(err, expr.Token, ErrorKindType)
+	}
+	return val, nil
+}
+
+// evalBinaryOperator applies a non short-circuit binary operator to evaluated operands.
+func (i *Interpreter) evalBinaryOperator(operator string, left, right Value) (Value, error) {
+	switch operator {
 	case "+":
 		return i.evalPlus(left, right)
 	case "-":
@@ -529,10 +1051,16 @@ func (i *Interpreter) evalBinaryExpr(expr *ast.BinaryExpr) (Value, error) {
 		return i.evalArithmetic(left, right, "/")
 	case "%":
 		return i.evalArithmetic(left, right, "%")
+	case "**":
+		return evalPower(left, right)
+	case "&", "|", "^", "<<", ">>":
+		return evalBitwise(left, right, operator)
+	case "in":
+		return evalIn(left, right)
 	case "==":
-		return left == right, nil
+		return valuesEqual(left, right), nil
 	case "!=":
-		return left != right, nil
+		return !valuesEqual(left, right), nil
 	case "<":
 		return i.evalComparison(left, right, "<")
 	case ">":
@@ -542,7 +1070,7 @@ func (i *Interpreter) evalBinaryExpr(expr *ast.BinaryExpr) (Value, error) {
 	case ">=":
 		return i.evalComparison(left, right, ">=")
 	default:
-		return nil, fmt.Errorf("unknown operator: %s", expr.Operator)
+		return nil, fmt.Errorf("unknown operator: %s", operator)
 	}
 }
 
@@ -556,6 +1084,14 @@ func (i *Interpreter) evalPlus(left, right Value) (Value, error) {
 	}
 
 	// Numeric addition
+	if ld, rd, ok := decimalOperands(left, right); ok {
+		return evalDecimalArithmetic(ld, rd, "+")
+	}
+	if li, ok := convert.Integer(left); ok {
+		if ri, ok := convert.Integer(right); ok {
+			return evalIntegerArithmetic(li, ri, "+")
+		}
+	}
 	leftNum, lok := toNumber(left)
 	rightNum, rok := toNumber(right)
 	if lok && rok {
@@ -565,26 +1101,12 @@ func (i *Interpreter) evalPlus(left, right Value) (Value, error) {
 	return nil, fmt.Errorf("cannot add %T and %T", left, right)
 }
 
-// toString converts a value to string efficiently without fmt.Sprintf
+// toString converts a value to string the way print shows it.
 func toString(val Value) string {
-	switch v := val.(type) {
-	case string:
-		return v
-	case float64:
-		if v == float64(int64(v)) {
-			return strconv.FormatInt(int64(v), 10)
-		}
-		return strconv.FormatFloat(v, 'f', -1, 64)
-	case bool:
-		if v {
-			return "true"
-		}
-		return "false"
-	case nil:
-		return "null"
-	default:
-		return fmt.Sprintf("%v", val)
+	if s, ok := val.(string); ok {
+		return s
 	}
+	return convert.String(val)
 }
 
 func (i *Interpreter) evalArithmetic(left, right Value, op string) (Value, error) {
@@ -598,18 +1120,27 @@ func (i *Interpreter) evalArithmetic(left, right Value, op string) (Value, error
 				return lf * rf, nil
 			case "/":
 				if rf == 0 {
-					return nil, fmt.Errorf("division by zero")
+					return nil, newRuntimeError(ErrorKindArithmetic, "division by zero")
 				}
 				return lf / rf, nil
 			case "%":
 				if rf == 0 {
-					return nil, fmt.Errorf("modulo by zero")
+					return nil, newRuntimeError(ErrorKindArithmetic, "modulo by zero")
 				}
 				return math.Mod(lf, rf), nil
 			}
 		}
 	}
 
+	if ld, rd, ok := decimalOperands(left, right); ok {
+		return evalDecimalArithmetic(ld, rd, op)
+	}
+	if li, ok := convert.Integer(left); ok {
+		if ri, ok := convert.Integer(right); ok {
+			return evalIntegerArithmetic(li, ri, op)
+		}
+	}
+
 	// Fallback to toNumber conversion
 	leftNum, lok := toNumber(left)
 	rightNum, rok := toNumber(right)
@@ -624,12 +1155,12 @@ func (i *Interpreter) evalArithmetic(left, right Value, op string) (Value, error
 		return leftNum * rightNum, nil
 	case "/":
 		if rightNum == 0 {
-			return nil, fmt.Errorf("division by zero")
+			return nil, newRuntimeError(ErrorKindArithmetic, "division by zero")
 		}
 		return leftNum / rightNum, nil
 	case "%":
 		if rightNum == 0 {
-			return nil, fmt.Errorf("modulo by zero")
+			return nil, newRuntimeError(ErrorKindArithmetic, "modulo by zero")
 		}
 		return math.Mod(leftNum, rightNum), nil
 	}
@@ -653,11 +1184,30 @@ func (i *Interpreter) evalComparison(left, right Value, op string) (Value, error
 		}
 	}
 
+	if ld, rd, ok := decimalOperands(left, right); ok {
+		return compareDecimals(ld, rd, op), nil
+	}
+	if li, ok := convert.Integer(left); ok {
+		if ri, ok := convert.Integer(right); ok {
+			return compareIntegers(li, ri, op), nil
+		}
+	}
+	if lb, ok := convert.BigInteger(left); ok {
+		if rb, ok := convert.BigInteger(right); ok {
+			return compareIntegers(int64(lb.Cmp(rb)), 0, op), nil
+		}
+	}
+
 	// Fallback to toNumber conversion
 	leftNum, lok := toNumber(left)
 	rightNum, rok := toNumber(right)
 	if !lok || !rok {
-		return nil, fmt.Errorf("cannot compare %T and %T", left, right)
+		// Strings, booleans and host values share the ordering of sort
+		c, err := natives.Compare(left, right, i.collation)
+		if err != nil {
+			return nil, err
+		}
+		return compareIntegers(int64(c), 0, op), nil
 	}
 
 	switch op {
@@ -681,34 +1231,63 @@ func (i *Interpreter) evalUnaryExpr(expr *ast.UnaryExpr) (Value, error) {
 
 	switch expr.Operator {
 	case "-":
+		if n, ok := convert.Integer(right); ok && n != math.MinInt64 {
+			return -n, nil
+		}
+		if d, ok := right.(*big.Rat); ok {
+			return new(big.Rat).Neg(d), nil
+		}
 		if num, ok := toNumber(right); ok {
 			return -num, nil
 		}
-		return nil, fmt.Errorf("cannot negate %T", right)
+		return nil, wrapError(fmt.Errorf("cannot negate %T", right), expr.Token, ErrorKindType)
 	case "!":
 		return !isTruthy(right), nil
+	case "~":
+		n, ok := integralOperand(right)
+		if !ok {
+			return nil, wrapError(integerOperandError("~", right), expr.Token, ErrorKindType)
+		}
+		return ^n, nil
 	}
 
 	return nil, fmt.Errorf("unknown unary operator: %s", expr.Operator)
 }
 
-func (i *Interpreter) evalSafeAccess(expr *ast.SafeAccessExpr) (Value, error) {
-	object, err := i.evalExpression(expr.Object)
-	if err != nil {
-		return nil, err
+// evalChainLink evaluates a property access, index or call, or the object
+// such a link is applied to. skipped reports that a null-safe link (?.)
+// met null: the rest of the chain is not evaluated and the whole chain is null.
+func (i *Interpreter) evalChainLink(expr ast.Expression) (val Value, skipped bool, err error) {
+	switch e := expr.(type) {
+	case *ast.SafeAccessExpr:
+		return i.evalSafeAccess(e)
+	case *ast.PropertyAccessExpr:
+		return i.evalPropertyAccess(e)
+	case *ast.IndexExpr:
+		return i.evalIndex(e)
+	case *ast.CallExpr:
+		return i.evalCallExpr(e)
 	}
+	val, err = i.evalExpression(expr)
+	return val, false, err
+}
 
-	// If object is null, return null (safe navigation)
-	if object == nil {
-		return nil, nil
+func (i *Interpreter) evalSafeAccess(expr *ast.SafeAccessExpr) (Value, bool, error) {
+	object, skipped, err := i.evalChainLink(expr.Object)
+	if err != nil || skipped {
+		return nil, skipped, err
 	}
 
-	// Try to access property on map
-	if m, ok := object.(map[string]interface{}); ok {
-		return m[expr.Property.Value], nil
+	// If object is null, the chain stops here (safe navigation)
+	if object == nil {
+		return nil, true, nil
 	}
 
-	return nil, nil
+	val, err := i.getMember(object, expr.Property.Value)
+	if err != nil {
+		return nil, false, wrapError(err, expr.Token, ErrorKindType)
+	}
+	return val, false, nil
 }
 
 func (i *Interpreter) evalElvisExpr(expr *ast.ElvisExpr) (Value, error) {
@@ -724,90 +1303,474 @@ func (i *Interpreter) evalElvisExpr(expr *ast.ElvisExpr) (Value, error) {
 	return i.evalExpression(expr.Default)
 }
 
-func (i *Interpreter) evalPropertyAccess(expr *ast.PropertyAccessExpr) (Value, error) {
-	object, err := i.evalExpression(expr.Object)
+func (i *Interpreter) evalPropertyAccess(expr *ast.PropertyAccessExpr) (Value, bool, error) {
+	object, skipped, err := i.evalChainLink(expr.Object)
+	if err != nil || skipped {
+		return nil, skipped, err
+	}
+
+	// Maps first, then reflection for methods and fields on Go objects
+	val, err := i.getMember(object, expr.Property.Value)
+	if err != nil {
+		return nil, false, wrapError(err, expr.Token, ErrorKindType)
+	}
+	return val, false, nil
+}
+
+func (i *Interpreter) evalIndex(expr *ast.IndexExpr) (Value, bool, error) {
+	left, skipped, err := i.evalChainLink(expr.Left)
+	if err != nil || skipped {
+		return nil, skipped, err
+	}
+	if left == nil && expr.Optional {
+		return nil, true, nil
+	}
+
+	index, err := i.evalExpression(expr.Index)
+	if err != nil {
+		return nil, false, err
+	}
+	val, err := i.evalIndexExpression(left, index)
+	if err != nil {
+		return nil, false, wrapError(err, expr.Token, ErrorKindType)
+	}
+	return val, false, nil
+}
+
+// evalAssignment evaluates x = expr and compound forms such as x += expr.
+func (i *Interpreter) evalAssignment(stmt *ast.Assignment) (Value, error) {
+	if i.env.IsConst(stmt.Name.Value) {
+		return nil, wrapError(fmt.Errorf("cannot assign to constant '%s'", stmt.Name.Value), stmt.Token, ErrorKindType)
+	}
+
+	var current Value
+	compound := isCompoundAssignment(stmt.Operator)
+	if compound {
+		var err error
+		current, err = i.evalExpression(stmt.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	val, err := i.evalExpression(stmt.Value)
 	if err != nil {
 		return nil, err
 	}
 
-	if object == nil {
-		return nil, fmt.Errorf("cannot access property '%s' on null", expr.Property.Value)
+	if compound {
+		val, err = i.evalBinaryOperator(strings.TrimSuffix(stmt.Operator, "="), current, val)
+		if err != nil {
+			return nil, wrapError(err, stmt.Token, ErrorKindType)
+		}
 	}
 
-	// First check for map access (existing behavior)
-	if m, ok := object.(map[string]interface{}); ok {
-		return m[expr.Property.Value], nil
+	i.assignVariable(stmt.Name.Value, val)
+	return val, nil
+}
+
+// assignVariable stores val in an existing variable, or declares it when undeclared.
+func (i *Interpreter) assignVariable(name string, val Value) {
+	if i.legacyScoping {
+		i.env.Set(name, val)
+		return
+	}
+	i.env.Assign(name, val)
+}
+
+// evalMemberAssignment evaluates obj.key = expr and arr[i] = expr, including compound forms.
+// The container and key are evaluated only once.
+func (i *Interpreter) evalMemberAssignment(stmt *ast.MemberAssignment) (Value, error) {
+	parent, container, key, err := i.resolveMember(stmt.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := i.evalExpression(stmt.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	if isCompoundAssignment(stmt.Operator) {
+		current, err := i.getMember(container, key)
+		if err != nil {
+			return nil, wrapError(err, stmt.Token, ErrorKindType)
+		}
+		val, err = i.evalBinaryOperator(strings.TrimSuffix(stmt.Operator, "="), current, val)
+		if err != nil {
+			return nil, wrapError(err, stmt.Token, ErrorKindType)
+		}
+	}
+
+	if err := i.storeMember(parent, container, key, val); err != nil {
+		return nil, wrapError(err, stmt.Token, ErrorKindType)
+	}
+	return val, nil
+}
+
+// isCompoundAssignment reports whether an assignment operator combines with the current value.
+func isCompoundAssignment(operator string) bool {
+	return operator != "" && operator != "="
+}
+
+// resolveMember evaluates the container and key of a property or index target,
+// returning the container expression so a replaced container can be stored back.
+func (i *Interpreter) resolveMember(target ast.Expression) (ast.Expression, Value, Value, error) {
+	switch t := target.(type) {
+	case *ast.PropertyAccessExpr:
+		obj, err := i.evalExpression(t.Object)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return t.Object, obj, t.Property.Value, nil
+
+	case *ast.IndexExpr:
+		obj, err := i.evalExpression(t.Left)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		index, err := i.evalExpression(t.Index)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return t.Left, obj, index, nil
+
+	default:
+		return nil, nil, nil, fmt.Errorf("invalid assignment target: %T", target)
+	}
+}
+
+// getMember reads key from container the same way property and index access do.
+func (i *Interpreter) getMember(container, key Value) (Value, error) {
+	if container == nil {
+		return nil, fmt.Errorf("cannot access property '%v' on null", key)
+	}
+	if name, ok := key.(string); ok {
+		if _, isMap := container.(map[string]interface{}); !isMap {
+			return i.reflectivePropertyAccess(container, name)
+		}
+	}
+	return i.evalIndexExpression(container, key)
+}
+
+// assignTo stores a value into an assignable expression: a variable, a property or an index.
+func (i *Interpreter) assignTo(target ast.Expression, val Value) error {
+	if ident, ok := target.(*ast.Identifier); ok {
+		i.assignVariable(ident.Value, val)
+		return nil
+	}
+
+	parent, container, key, err := i.resolveMember(target)
+	if err != nil {
+		return err
+	}
+	return i.storeMember(parent, container, key, val)
+}
+
+// storeMember sets key on container. When the container has to be replaced
+// (appending to an array, updating a copied struct), the new container is
+// written back into its parent expression, which must then be assignable.
+// Only that variable or property sees an appended element: another variable
+// holding the same array keeps its previous length.
+func (i *Interpreter) storeMember(parent ast.Expression, container, key, val Value) error {
+	updated, replaced, err := setMember(container, key, val)
+	if err != nil {
+		return err
+	}
+	if !replaced {
+		return nil
+	}
+	switch parent.(type) {
+	case *ast.Identifier, *ast.PropertyAccessExpr, *ast.IndexExpr:
+		return i.assignTo(parent, updated)
+	}
+	if _, ok := container.([]interface{}); ok {
+		return fmt.Errorf("cannot append to an array that is not stored in a variable, property or index")
+	}
+	return fmt.Errorf("cannot update a copy of %T that is not stored in a variable, property or index", container)
+}
+
+// setMember sets key on container. It returns the container to store back
+// and whether it differs from the original (arrays grow by appending).
+func setMember(container, key, val Value) (Value, bool, error) {
+	switch c := container.(type) {
+	case nil:
+		return nil, false, fmt.Errorf("cannot set property '%v' on null", key)
+
+	case map[string]interface{}:
+		k, ok := key.(string)
+		if !ok {
+			return nil, false, fmt.Errorf("object key must be a string, got %T", key)
+		}
+		c[k] = val
+		return c, false, nil
+
+	case []interface{}:
+		num, ok := toNumber(key)
+		if !ok || num != math.Trunc(num) {
+			return nil, false, fmt.Errorf("array index must be an integer")
+		}
+		idx := int(num)
+		switch {
+		case idx >= 0 && idx < len(c):
+			c[idx] = val
+			return c, false, nil
+		case idx == len(c):
+			// Never grow into spare capacity another alias may share
+			return append(c[:len(c):len(c)], val), true, nil
+		default:
+			return nil, false, fmt.Errorf("array index out of range: %d (length %d)", idx, len(c))
+		}
+	}
+
+	if collection, ok := convert.Collection(container); ok {
+		return reflectiveIndexAssign(container, collection, key, val)
+	}
+	name, ok := key.(string)
+	if !ok {
+		return nil, false, fmt.Errorf("index assignment not supported: %T", container)
 	}
+	return reflectiveFieldAssign(container, name, val)
+}
 
-	// Use reflection to access methods and fields on Go objects
-	return i.reflectivePropertyAccess(object, expr.Property.Value)
+func (i *Interpreter) evalCallExpr(expr *ast.CallExpr) (Value, bool, error) {
+	val, skipped, err := i.evalCall(expr)
+	if err != nil {
+		return nil, false, wrapError(err, expr.Token, ErrorKindRuntime)
+	}
+	return val, skipped, nil
 }
 
-func (i *Interpreter) evalCallExpr(expr *ast.CallExpr) (Value, error) {
+func (i *Interpreter) evalCall(expr *ast.CallExpr) (Value, bool, error) {
 	// Special handling for print (keep it special to capture output in env)
 	if ident, ok := expr.Function.(*ast.Identifier); ok && ident.Value == "print" {
-		args := make([]Value, len(expr.Arguments))
-		for idx, arg := range expr.Arguments {
-			val, err := i.evalExpression(arg)
-			if err != nil {
-				return nil, err
-			}
-			args[idx] = val
+		args, err := i.evalExpressionList(expr.Arguments)
+		if err != nil {
+			return nil, false, err
 		}
 
 		for _, arg := range args {
 			output := toString(arg)
 			fmt.Println(output)
-			i.env.AddOutput(output)
+			i.globals.AddOutput(output)
 		}
-		return nil, nil
+		return nil, false, nil
 	}
 
 	// Special handling for higher-order array functions
 	if ident, ok := expr.Function.(*ast.Identifier); ok {
+		var higherOrder func([]Value) (Value, error)
 		switch ident.Value {
 		case "map":
-			return i.evalMapFunction(expr)
+			higherOrder = i.evalMapFunction
 		case "filter":
-			return i.evalFilterFunction(expr)
+			higherOrder = i.evalFilterFunction
 		case "reduce":
-			return i.evalReduceFunction(expr)
+			higherOrder = i.evalReduceFunction
 		case "find":
-			return i.evalFindFunction(expr)
+			higherOrder = i.evalFindFunction
 		case "findIndex":
-			return i.evalFindIndexFunction(expr)
+			higherOrder = i.evalFindIndexFunction
 		}
+		if higherOrder != nil {
+			args, err := i.evalExpressionList(expr.Arguments)
+			if err != nil {
+				return nil, false, err
+			}
+			val, err := higherOrder(args)
+			return val, false, err
+		}
+	}
+
+	function, skipped, err := i.evalChainLink(expr.Function)
+	if err != nil || skipped {
+		return nil, skipped, err
+	}
+	if function == nil && expr.Optional {
+		return nil, true, nil
 	}
 
-	function, err := i.evalExpression(expr.Function)
+	args, err := i.evalExpressionList(expr.Arguments)
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+
+	if fn, ok := function.(*Function); ok && i.strictArity {
+		if err := checkArity(fn, len(args)); err != nil {
+			return nil, false, err
+		}
 	}
 
-	args := make([]Value, len(expr.Arguments))
-	for idx, arg := range expr.Arguments {
+	val, err := i.applyFunction(function, args)
+	return val, false, err
+}
+
+// evalExpressionList evaluates array elements or call arguments, expanding
+// spreads (...arr) in place.
+func (i *Interpreter) evalExpressionList(exprs []ast.Expression) ([]Value, error) {
+	args := make([]Value, 0, len(exprs))
+	for _, arg := range exprs {
+		if spread, ok := arg.(*ast.SpreadExpr); ok {
+			items, err := i.evalSpreadArray(spread)
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range items {
+				args = append(args, item)
+			}
+			continue
+		}
 		val, err := i.evalExpression(arg)
 		if err != nil {
 			return nil, err
 		}
-		args[idx] = val
+		args = append(args, val)
 	}
+	return args, nil
+}
+
+// evalObjectLiteral builds an object from its entries in source order, so
+// later keys and spreads override earlier ones.
+func (i *Interpreter) evalObjectLiteral(e *ast.ObjectLiteral) (Value, error) {
+	pairs := make(map[string]interface{}, len(e.Entries))
+	for _, entry := range e.Entries {
+		spread, ok := entry.Value.(*ast.SpreadExpr)
+		if !ok {
+			val, err := i.evalExpression(entry.Value)
+			if err != nil {
+				return nil, err
+			}
+			pairs[entry.Key] = val
+			continue
+		}
 
-	return i.applyFunction(function, args)
+		val, err := i.evalExpression(spread.Value)
+		if err != nil {
+			return nil, err
+		}
+		switch src := val.(type) {
+		case map[string]interface{}:
+			for key, v := range src {
+				pairs[key] = v
+			}
+		case nil:
+			// Spreading null adds nothing, like {...undefined} in JS
+		default:
+			return nil, wrapError(fmt.Errorf("cannot spread %T into an object", val), spread.Token, ErrorKindType)
+		}
+	}
+	return pairs, nil
+}
+
+// evalSpreadArray evaluates the operand of a spread in an array literal or call.
+func (i *Interpreter) evalSpreadArray(spread *ast.SpreadExpr) ([]interface{}, error) {
+	val, err := i.evalExpression(spread.Value)
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := convert.ToArray(val)
+	if !ok {
+		if rng, isRange := val.(Range); isRange {
+			return nil, wrapError(tooLongRangeError(rng), spread.Token, ErrorKindType)
+		}
+		return nil, wrapError(fmt.Errorf("cannot spread %T into an array or argument list", val), spread.Token, ErrorKindType)
+	}
+	return arr, nil
+}
+
+// tooLongRangeError reports a range with too many values to expand into an array.
+func tooLongRangeError(rng Range) error {
+	return fmt.Errorf("range %s has more than %d values to expand into an array", rng, convert.MaxSequenceLength)
+}
+
+// iterableArgument returns the elements a higher-order function walks:
+// those of an array or a range. It reports whether the argument is null,
+// which walks nothing, and fails for anything else.
+func iterableArgument(name string, val Value) ([]interface{}, bool, error) {
+	if val == nil {
+		return nil, true, nil
+	}
+	if arr, ok := convert.ToArray(val); ok {
+		return arr, false, nil
+	}
+	if rng, ok := val.(Range); ok {
+		return nil, false, tooLongRangeError(rng)
+	}
+	return nil, false, fmt.Errorf("%s requires an array or a range, got %T", name, val)
+}
+
+// bindParameters binds call arguments in the current (function) environment.
+// Missing arguments take their default value, evaluated so that it can
+// refer to earlier parameters, or null when there is none.
+func (i *Interpreter) bindParameters(function *Function, args []Value) error {
+	for idx, param := range function.Parameters {
+		var val Value
+		switch {
+		case idx < len(args):
+			val = args[idx]
+		case idx < len(function.Defaults) && function.Defaults[idx] != nil:
+			def, err := i.evalExpression(function.Defaults[idx])
+			if err != nil {
+				return err
+			}
+			val = def
+		}
+		i.env.Set(param.Value, val)
+	}
+	if function.Rest != nil {
+		rest := []interface{}{}
+		for idx := len(function.Parameters); idx < len(args); idx++ {
+			rest = append(rest, args[idx])
+		}
+		i.env.Set(function.Rest.Value, rest)
+	}
+	return nil
+}
+
+// checkArity reports a call whose argument count does not fit the
+// function's signature. Only used in strict arity mode.
+func checkArity(function *Function, argc int) error {
+	required := 0
+	for idx := range function.Parameters {
+		if idx >= len(function.Defaults) || function.Defaults[idx] == nil {
+			required = idx + 1
+		}
+	}
+	maximum := len(function.Parameters)
+	if argc >= required && (function.Rest != nil || argc <= maximum) {
+		return nil
+	}
+
+	var expected string
+	switch {
+	case function.Rest != nil:
+		expected = fmt.Sprintf("at least %d", required)
+	case required == maximum:
+		expected = fmt.Sprintf("%d", maximum)
+	default:
+		expected = fmt.Sprintf("%d to %d", required, maximum)
+	}
+	noun := "arguments"
+	if expected == "1" || expected == "at least 1" {
+		noun = "argument"
+	}
+	name := "function"
+	if function.Name != "" {
+		name = fmt.Sprintf("function '%s'", function.Name)
+	}
+	return newRuntimeError(ErrorKindType, fmt.Sprintf("%s expects %s %s, got %d", name, expected, noun, argc))
 }
 
 func (i *Interpreter) applyFunction(fn Value, args []Value) (Value, error) {
 	switch function := fn.(type) {
 	case *Function:
-		extendedEnv := NewEnclosedEnvironment(function.Env)
-		for idx, param := range function.Parameters {
-			if idx < len(args) {
-				extendedEnv.Set(param.Value, args[idx])
-			}
-		}
 		savedEnv := i.env
-		i.env = extendedEnv
-		val, err := i.evalBlockStatement(function.Body)
+		i.env = NewEnclosedEnvironment(function.Env)
+		var val Value
+		err := i.bindParameters(function, args)
+		if err == nil {
+			val, err = i.evalBlockStatement(function.Body)
+		}
 		i.env = savedEnv // Restore env
 		if err != nil {
 			return nil, err
@@ -815,6 +1778,9 @@ func (i *Interpreter) applyFunction(fn Value, args []Value) (Value, error) {
 		if rv, ok := val.(*ReturnValue); ok {
 			return rv.Value, nil
 		}
+		if err := loopSignalError(val); err != nil {
+			return nil, err
+		}
 		return val, nil
 
 	case *NativeFunction:
@@ -822,10 +1788,14 @@ func (i *Interpreter) applyFunction(fn Value, args []Value) (Value, error) {
 		for i, arg := range args {
 			ifaceArgs[i] = arg
 		}
-		return function.Fn(ifaceArgs...)
+		val, err := function.Fn(ifaceArgs...)
+		if err != nil && isCatchable(err) {
+			return nil, &RuntimeError{Kind: ErrorKindNative, Message: err.Error(), Cause: err}
+		}
+		return val, err
 
 	default:
-		return nil, fmt.Errorf("not a function: %T", fn)
+		return nil, newRuntimeError(ErrorKindType, fmt.Sprintf("not a function: %T", fn))
 	}
 }
 
@@ -836,14 +1806,42 @@ func (i *Interpreter) evalIndexExpression(left, index Value) (Value, error) {
 	case map[string]interface{}: // map[string]Value is alias to map[string]interface{}
 		return i.evalHashIndexExpression(l, index)
 	default:
+		if rv, ok := convert.Collection(left); ok {
+			return i.evalGoIndexExpression(rv, index)
+		}
 		return nil, fmt.Errorf("index operator not supported: %T", left)
 	}
 }
 
+// evalGoIndexExpression indexes a Go slice, array or map bound by the host.
+// Elements are converted as they are read; out of range and missing keys are null.
+func (i *Interpreter) evalGoIndexExpression(collection reflect.Value, index Value) (Value, error) {
+	if collection.Kind() == reflect.Map {
+		key, ok := index.(string)
+		if !ok {
+			return nil, fmt.Errorf("property access must be a string")
+		}
+		val, _ := mapIndex(collection, key)
+		return val, nil
+	}
+
+	num, ok := toNumber(index)
+	if !ok {
+		return nil, fmt.Errorf("index must be a number")
+	}
+	idx := int(num)
+	if idx < 0 || idx >= collection.Len() {
+		return nil, nil
+	}
+	return convert.FromGo(collection.Index(idx)), nil
+}
+
 func (i *Interpreter) evalArrayIndexExpression(array []interface{}, index Value) (Value, error) {
 	var idx int
 
 	switch iVal := index.(type) {
+	case int64:
+		idx = int(iVal)
 	case int:
 		idx = iVal
 	case float64:
@@ -886,38 +1884,44 @@ func toNumber(val Value) (float64, bool) {
 	switch v := val.(type) {
 	case float64:
 		return v, true
-	case int:
-		return float64(v), true
 	case int64:
 		return float64(v), true
+	case float32:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case *big.Rat:
+		f, _ := v.Float64()
+		return f, true
+	}
+	if n, ok := convert.Integer(val); ok {
+		return float64(n), true
 	}
 	return 0, false
 }
 
 // ============ Higher-order array functions ============
 
-func (i *Interpreter) evalMapFunction(expr *ast.CallExpr) (Value, error) {
-	if len(expr.Arguments) < 2 {
+func (i *Interpreter) evalMapFunction(args []Value) (Value, error) {
+	if len(args) < 2 {
 		return nil, fmt.Errorf("map requires 2 arguments: array and function")
 	}
 
-	arrVal, err := i.evalExpression(expr.Arguments[0])
+	arr, isNull, err := iterableArgument("map", args[0])
 	if err != nil {
 		return nil, err
 	}
-	arr, ok := arrVal.([]interface{})
-	if !ok {
+	if isNull {
 		return []interface{}{}, nil
 	}
 
-	fnVal, err := i.evalExpression(expr.Arguments[1])
-	if err != nil {
-		return nil, err
-	}
+	fnVal := args[1]
 
 	result := make([]interface{}, len(arr))
 	for idx, item := range arr {
-		val, err := i.applyFunction(fnVal, []Value{item, float64(idx)})
+		val, err := i.applyFunction(fnVal, []Value{item, int64(idx)})
 		if err != nil {
 			return nil, err
 		}
@@ -926,28 +1930,24 @@ func (i *Interpreter) evalMapFunction(expr *ast.CallExpr) (Value, error) {
 	return result, nil
 }
 
-func (i *Interpreter) evalFilterFunction(expr *ast.CallExpr) (Value, error) {
-	if len(expr.Arguments) < 2 {
+func (i *Interpreter) evalFilterFunction(args []Value) (Value, error) {
+	if len(args) < 2 {
 		return nil, fmt.Errorf("filter requires 2 arguments: array and function")
 	}
 
-	arrVal, err := i.evalExpression(expr.Arguments[0])
+	arr, isNull, err := iterableArgument("filter", args[0])
 	if err != nil {
 		return nil, err
 	}
-	arr, ok := arrVal.([]interface{})
-	if !ok {
+	if isNull {
 		return []interface{}{}, nil
 	}
 
-	fnVal, err := i.evalExpression(expr.Arguments[1])
-	if err != nil {
-		return nil, err
-	}
+	fnVal := args[1]
 
 	result := []interface{}{}
 	for idx, item := range arr {
-		val, err := i.applyFunction(fnVal, []Value{item, float64(idx)})
+		val, err := i.applyFunction(fnVal, []Value{item, int64(idx)})
 		if err != nil {
 			return nil, err
 		}
@@ -958,32 +1958,25 @@ func (i *Interpreter) evalFilterFunction(expr *ast.CallExpr) (Value, error) {
 	return result, nil
 }
 
-func (i *Interpreter) evalReduceFunction(expr *ast.CallExpr) (Value, error) {
-	if len(expr.Arguments) < 3 {
+func (i *Interpreter) evalReduceFunction(args []Value) (Value, error) {
+	if len(args) < 3 {
 		return nil, fmt.Errorf("reduce requires 3 arguments: array, function, and initial value")
 	}
 
-	arrVal, err := i.evalExpression(expr.Arguments[0])
+	arr, isNull, err := iterableArgument("reduce", args[0])
 	if err != nil {
 		return nil, err
 	}
-	arr, ok := arrVal.([]interface{})
-	if !ok {
+	if isNull {
 		return nil, nil
 	}
 
-	fnVal, err := i.evalExpression(expr.Arguments[1])
-	if err != nil {
-		return nil, err
-	}
-
-	accumulator, err := i.evalExpression(expr.Arguments[2])
-	if err != nil {
-		return nil, err
-	}
+	fnVal := args[1]
+	accumulator := args[2]
 
 	for idx, item := range arr {
-		accumulator, err = i.applyFunction(fnVal, []Value{accumulator, item, float64(idx)})
+		var err error
+		accumulator, err = i.applyFunction(fnVal, []Value{accumulator, item, int64(idx)})
 		if err != nil {
 			return nil, err
 		}
@@ -991,27 +1984,23 @@ func (i *Interpreter) evalReduceFunction(expr *ast.CallExpr) (Value, error) {
 	return accumulator, nil
 }
 
-func (i *Interpreter) evalFindFunction(expr *ast.CallExpr) (Value, error) {
-	if len(expr.Arguments) < 2 {
+func (i *Interpreter) evalFindFunction(args []Value) (Value, error) {
+	if len(args) < 2 {
 		return nil, fmt.Errorf("find requires 2 arguments: array and function")
 	}
 
-	arrVal, err := i.evalExpression(expr.Arguments[0])
+	arr, isNull, err := iterableArgument("find", args[0])
 	if err != nil {
 		return nil, err
 	}
-	arr, ok := arrVal.([]interface{})
-	if !ok {
+	if isNull {
 		return nil, nil
 	}
 
-	fnVal, err := i.evalExpression(expr.Arguments[1])
-	if err != nil {
-		return nil, err
-	}
+	fnVal := args[1]
 
 	for idx, item := range arr {
-		val, err := i.applyFunction(fnVal, []Value{item, float64(idx)})
+		val, err := i.applyFunction(fnVal, []Value{item, int64(idx)})
 		if err != nil {
 			return nil, err
 		}
@@ -1022,33 +2011,29 @@ func (i *Interpreter) evalFindFunction(expr *ast.CallExpr) (Value, error) {
 	return nil, nil
 }
 
-func (i *Interpreter) evalFindIndexFunction(expr *ast.CallExpr) (Value, error) {
-	if len(expr.Arguments) < 2 {
+func (i *Interpreter) evalFindIndexFunction(args []Value) (Value, error) {
+	if len(args) < 2 {
 		return nil, fmt.Errorf("findIndex requires 2 arguments: array and function")
 	}
 
-	arrVal, err := i.evalExpression(expr.Arguments[0])
+	arr, isNull, err := iterableArgument("findIndex", args[0])
 	if err != nil {
 		return nil, err
 	}
-	arr, ok := arrVal.([]interface{})
-	if !ok {
-		return float64(-1), nil
+	if isNull {
+		return int64(-1), nil
 	}
 
-	fnVal, err := i.evalExpression(expr.Arguments[1])
-	if err != nil {
-		return nil, err
-	}
+	fnVal := args[1]
 
 	for idx, item := range arr {
-		val, err := i.applyFunction(fnVal, []Value{item, float64(idx)})
+		val, err := i.applyFunction(fnVal, []Value{item, int64(idx)})
 		if err != nil {
 			return nil, err
 		}
 		if isTruthy(val) {
-			return float64(idx), nil
+			return int64(idx), nil
 		}
 	}
-	return float64(-1), nil
+	return int64(-1), nil
 }
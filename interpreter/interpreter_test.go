@@ -1,10 +1,16 @@
 package interpreter
 
 import (
+	"errors"
+	"math"
+	"math/big"
+	"strings"
 	"testing"
 
 	"github.com/issadicko/kodi-script-go/ast"
+	"github.com/issadicko/kodi-script-go/decimal"
 	"github.com/issadicko/kodi-script-go/lexer"
+	"github.com/issadicko/kodi-script-go/natives"
 	"github.com/issadicko/kodi-script-go/parser"
 )
 
@@ -33,16 +39,16 @@ func TestBasicExpressions(t *testing.T) {
 		source   string
 		expected Value
 	}{
-		{"42", float64(42)},
+		{"42", int64(42)},
 		{`"hello"`, "hello"},
 		{"true", true},
 		{"false", false},
 		{"null", nil},
-		{"5 + 3", float64(8)},
-		{"10 - 4", float64(6)},
-		{"3 * 4", float64(12)},
-		{"20 / 5", float64(4)},
-		{"-5", float64(-5)},
+		{"5 + 3", int64(8)},
+		{"10 - 4", int64(6)},
+		{"3 * 4", int64(12)},
+		{"20 / 5", int64(4)},
+		{"-5", int64(-5)},
 		{"!true", false},
 		{"!false", true},
 	}
@@ -136,7 +142,7 @@ x + y`, nil)
 	if err != nil {
 		t.Fatalf("error: %v", err)
 	}
-	if result != float64(30) {
+	if result != int64(30) {
 		t.Errorf("expected 30, got %v", result)
 	}
 }
@@ -148,7 +154,7 @@ x`, nil)
 	if err != nil {
 		t.Fatalf("error: %v", err)
 	}
-	if result != float64(20) {
+	if result != int64(20) {
 		t.Errorf("expected 20, got %v", result)
 	}
 }
@@ -162,7 +168,7 @@ x`, nil)
 	if err != nil {
 		t.Fatalf("error: %v", err)
 	}
-	if result != float64(100) {
+	if result != int64(100) {
 		t.Errorf("expected 100, got %v", result)
 	}
 }
@@ -178,7 +184,7 @@ x`, nil)
 	if err != nil {
 		t.Fatalf("error: %v", err)
 	}
-	if result != float64(50) {
+	if result != int64(50) {
 		t.Errorf("expected 50, got %v", result)
 	}
 }
@@ -191,7 +197,7 @@ y`, nil)
 	if err != nil {
 		t.Fatalf("error: %v", err)
 	}
-	if result != float64(20) {
+	if result != int64(20) {
 		t.Errorf("expected 20, got %v", result)
 	}
 }
@@ -270,6 +276,98 @@ func TestSafeAccess(t *testing.T) {
 	}
 }
 
+func TestOptionalChaining(t *testing.T) {
+	vars := map[string]interface{}{
+		"user":  map[string]interface{}{"name": "Alice", "tags": []interface{}{"a", "b"}},
+		"none":  nil,
+		"items": []interface{}{map[string]interface{}{"id": float64(7)}},
+	}
+
+	tests := []struct {
+		source   string
+		expected Value
+	}{
+		{`user?.name`, "Alice"},
+		{`none?.name`, nil},
+		{`none?.address.city`, nil},
+		{`none?.address.city.length`, nil},
+		{`none?.tags[0]`, nil},
+		{`none?.greet()`, nil},
+		{`user?.tags?.[1]`, "b"},
+		{`none?.[0]`, nil},
+		{`items?.[0]?.id`, float64(7)},
+		{`items?.[5]?.id`, nil},
+		{`user.missing?.[0].x`, nil},
+		{`let f = null
+f?.(1, 2)`, nil},
+		{`let f = fn(a, b) { a + b }
+f?.(1, 2)`, int64(3)},
+		{`let obj = {inc: x => x + 1}
+obj?.inc?.(1)`, int64(2)},
+		{`let calls = 0
+let count = fn() { calls++ }
+none?.[count()]
+none?.(count())
+calls`, int64(0)},
+		{`none?.name ?: "anonymous"`, "anonymous"},
+		{`user.greet?.()()`, nil},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(tt.source, vars)
+		if len(errs) > 0 {
+			t.Errorf("'%s': parse errors: %v", tt.source, errs)
+			continue
+		}
+		if err != nil {
+			t.Errorf("'%s': error: %v", tt.source, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("'%s': expected %v, got %v", tt.source, tt.expected, result)
+		}
+	}
+}
+
+func TestOptionalChainingErrors(t *testing.T) {
+	vars := map[string]interface{}{
+		"user": map[string]interface{}{"address": nil},
+	}
+
+	// ?. only guards the link it is written on
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{`user?.address.city`, "cannot access property 'city' on null"},
+		{`user?.greet()`, "not a function: <nil>"},
+		{`let x = 5
+x?.()`, "not a function: int64"},
+	}
+
+	for _, tt := range tests {
+		_, err, errs := parseAndEval(tt.source, vars)
+		if len(errs) > 0 {
+			t.Errorf("'%s': parse errors: %v", tt.source, errs)
+			continue
+		}
+		if err == nil {
+			t.Errorf("'%s': expected error", tt.source)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("'%s': expected error containing %q, got %q", tt.source, tt.expected, err.Error())
+		}
+	}
+
+	for _, source := range []string{"arr?.[0] = 1", "arr?.[0]++"} {
+		_, _, errs := parseAndEval(source, nil)
+		if len(errs) == 0 {
+			t.Errorf("'%s': expected parse error", source)
+		}
+	}
+}
+
 func TestPrintCapture(t *testing.T) {
 	l := lexer.New(`print("hello")
 print("world")`)
@@ -288,6 +386,36 @@ print("world")`)
 	}
 }
 
+func TestPrintNestedDecimals(t *testing.T) {
+	l := lexer.New(`print([1.5d, 2])
+print({x: 0.1d, list: [1d / 3]})
+print(toString([1.5d]))
+print("total: " + [19.99d])`)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	interp := NewWithEnv(map[string]interface{}{})
+	if _, err := interp.Eval(program); err != nil {
+		t.Fatalf("eval error: %v", err)
+	}
+
+	expected := []string{
+		"[1.5 2]",
+		"map[list:[0.33333333333333333333] x:0.1]",
+		"[1.5]",
+		"total: [19.99]",
+	}
+	output := interp.GetOutput()
+	if len(output) != len(expected) {
+		t.Fatalf("expected %d outputs, got %v", len(expected), output)
+	}
+	for idx, line := range expected {
+		if output[idx] != line {
+			t.Errorf("line %d: expected %q, got %q", idx+1, line, output[idx])
+		}
+	}
+}
+
 func TestEnvironment(t *testing.T) {
 	env := NewEnvironment()
 	env.Set("x", float64(10))
@@ -422,7 +550,7 @@ func TestMapFunction(t *testing.T) {
 		t.Fatalf("error: %v", err)
 	}
 	arr := result.([]interface{})
-	if len(arr) != 3 || arr[0] != float64(2) || arr[1] != float64(4) || arr[2] != float64(6) {
+	if len(arr) != 3 || arr[0] != int64(2) || arr[1] != int64(4) || arr[2] != int64(6) {
 		t.Errorf("expected [2, 4, 6], got %v", arr)
 	}
 }
@@ -437,7 +565,7 @@ func TestFilterFunction(t *testing.T) {
 		t.Fatalf("error: %v", err)
 	}
 	arr := result.([]interface{})
-	if len(arr) != 3 || arr[0] != float64(2) || arr[1] != float64(4) || arr[2] != float64(6) {
+	if len(arr) != 3 || arr[0] != int64(2) || arr[1] != int64(4) || arr[2] != int64(6) {
 		t.Errorf("expected [2, 4, 6], got %v", arr)
 	}
 }
@@ -451,7 +579,7 @@ func TestReduceFunction(t *testing.T) {
 	if err != nil {
 		t.Fatalf("error: %v", err)
 	}
-	if result != float64(10) {
+	if result != int64(10) {
 		t.Errorf("expected 10, got %v", result)
 	}
 }
@@ -464,7 +592,7 @@ func TestFindFunction(t *testing.T) {
 	if err != nil {
 		t.Fatalf("error: %v", err)
 	}
-	if result != float64(4) {
+	if result != int64(4) {
 		t.Errorf("expected 4, got %v", result)
 	}
 }
@@ -477,7 +605,7 @@ func TestFindIndexFunction(t *testing.T) {
 	if err != nil {
 		t.Fatalf("error: %v", err)
 	}
-	if result != float64(2) {
+	if result != int64(2) {
 		t.Errorf("expected 2, got %v", result)
 	}
 }
@@ -511,3 +639,1761 @@ func TestRepeatFunction(t *testing.T) {
 		t.Errorf("expected 'ababab', got %v", result)
 	}
 }
+
+func TestBreakStatement(t *testing.T) {
+	result, err, errs := parseAndEval(`let found = null
+for (x in [1, 2, 3, 4]) {
+    if (x > 2) {
+        found = x
+        break
+    }
+}
+found`, nil)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if result != int64(3) {
+		t.Errorf("expected 3, got %v", result)
+	}
+}
+
+func TestContinueStatement(t *testing.T) {
+	result, err, errs := parseAndEval(`let sum = 0
+let i = 0
+while (i < 5) {
+    i = i + 1
+    if (i == 2) {
+        continue
+    }
+    sum = sum + i
+}
+sum`, nil)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if result != int64(13) {
+		t.Errorf("expected 13, got %v", result)
+	}
+}
+
+func TestLabeledBreakAndContinue(t *testing.T) {
+	result, err, errs := parseAndEval(`let pairs = 0
+outer: for (a in [1, 2, 3]) {
+    for (b in [1, 2, 3]) {
+        if (b == 2) {
+            continue outer
+        }
+        if (a == 3) {
+            break outer
+        }
+        pairs = pairs + 1
+    }
+}
+pairs`, nil)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if result != int64(2) {
+		t.Errorf("expected 2, got %v", result)
+	}
+}
+
+func TestBreakOutsideLoop(t *testing.T) {
+	tests := []string{
+		"break",
+		"continue",
+		"let f = fn() { break }\nfor (x in [1]) { f() }",
+		"for (x in [1]) { break missing }",
+	}
+
+	for _, source := range tests {
+		_, err, errs := parseAndEval(source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", source, errs)
+		}
+		if err == nil {
+			t.Errorf("'%s': expected error for break/continue outside of a loop", source)
+		}
+	}
+}
+
+func TestElseIfChain(t *testing.T) {
+	source := `let tier = "none"
+if (points > 100) {
+    tier = "gold"
+} else if (points > 50) {
+    tier = "silver"
+} else if (points > 10) {
+    tier = "bronze"
+} else {
+    tier = "none"
+}
+tier`
+	tests := []struct {
+		points   float64
+		expected string
+	}{
+		{150, "gold"},
+		{75, "silver"},
+		{20, "bronze"},
+		{5, "none"},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(source, map[string]interface{}{"points": tt.points})
+		if len(errs) > 0 {
+			t.Fatalf("parse errors: %v", errs)
+		}
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if result != tt.expected {
+			t.Errorf("points=%v: expected %s, got %v", tt.points, tt.expected, result)
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	source := `match (value) {
+    "gold" -> "G"
+    1, 2 -> "small"
+    3..<10 -> "medium"
+    10..20 -> "large"
+    {tier: "silver", active: true} -> "active silver"
+    {tier: _} -> "has tier"
+    else -> {
+        let label = "other"
+        label
+    }
+}`
+	tests := []struct {
+		value    interface{}
+		expected interface{}
+	}{
+		{"gold", "G"},
+		{float64(2), "small"},
+		{float64(3), "medium"},
+		{float64(10), "large"},
+		{float64(20), "large"},
+		{map[string]interface{}{"tier": "silver", "active": true}, "active silver"},
+		{map[string]interface{}{"tier": "silver", "active": false}, "has tier"},
+		{map[string]interface{}{"name": "x"}, "other"},
+		{nil, "other"},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(source, map[string]interface{}{"value": tt.value})
+		if len(errs) > 0 {
+			t.Fatalf("parse errors: %v", errs)
+		}
+		if err != nil {
+			t.Fatalf("error for %v: %v", tt.value, err)
+		}
+		if result != tt.expected {
+			t.Errorf("%v: expected %v, got %v", tt.value, tt.expected, result)
+		}
+	}
+}
+
+func TestMatchObjectPatternSpread(t *testing.T) {
+	_, err, errs := parseAndEval(`let base = {tier: "gold"}
+match ({tier: "gold"}) { {...base} -> "gold" else -> "other" }`, nil)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if err == nil || !strings.Contains(err.Error(), "cannot spread into an object pattern") {
+		t.Errorf("expected a spread pattern error, got %v", err)
+	}
+}
+
+func TestMatchWithoutElse(t *testing.T) {
+	result, err, errs := parseAndEval(`let x = match (5) { 1 -> "one", 2 -> "two" }
+x`, nil)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if result != nil {
+		t.Errorf("expected null, got %v", result)
+	}
+}
+
+func TestMatchBreakInsideLoop(t *testing.T) {
+	result, err, errs := parseAndEval(`let count = 0
+for (x in [1, 2, 3, 4]) {
+    match (x) {
+        3 -> { break }
+        else -> { count = count + 1 }
+    }
+}
+count`, nil)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if result != int64(2) {
+		t.Errorf("expected 2, got %v", result)
+	}
+}
+
+func TestMatchControlFlowInExpression(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected Value
+	}{
+		{`fn f(x) {
+    let y = 1 + match (x) { 1 -> { return 10 } else -> 0 }
+    return y + 100
+}
+[f(1), f(2)]`, []interface{}{int64(10), int64(101)}},
+		{`fn g(x) {
+    try {
+        return [match (x) { 1 -> { return "early" } else -> "late" }]
+    } catch (e) {
+        return "caught"
+    }
+}
+g(1)`, "early"},
+		{`let seen = []
+for (x in [1, 2, 3, 4]) {
+    seen[size(seen)] = 10 * match (x) { 2 -> { continue } 4 -> { break } else -> x }
+}
+seen`, []interface{}{int64(10), int64(30)}},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors: %v", errs)
+		}
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if !valuesEqual(result, tt.expected) {
+			t.Errorf("expected %v, got %v", tt.expected, result)
+		}
+	}
+}
+
+func TestTryCatchRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		kind   string
+	}{
+		{"10 / 0", "ArithmeticError"},
+		{"missing + 1", "ReferenceError"},
+		{`jsonParse("{bad")`, "NativeError"},
+		{`let n = null
+n.name`, "TypeError"},
+	}
+
+	for _, tt := range tests {
+		source := "let kind = null\ntry {\n" + tt.source + "\n} catch (e) {\nkind = e.kind\n}\nkind"
+		result, err, errs := parseAndEval(source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %v", tt.source, err)
+		}
+		if result != tt.kind {
+			t.Errorf("'%s': expected kind %s, got %v", tt.source, tt.kind, result)
+		}
+	}
+}
+
+func TestThrowAndCatch(t *testing.T) {
+	result, err, errs := parseAndEval(`let caught = null
+try {
+    throw "invalid tier"
+} catch (e) {
+    caught = e
+}
+caught`, nil)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	obj, ok := result.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected error object, got %T", result)
+	}
+	if obj["message"] != "invalid tier" || obj["kind"] != "Error" {
+		t.Errorf("unexpected error object: %v", obj)
+	}
+	if obj["line"] != int64(3) || obj["column"] != int64(5) {
+		t.Errorf("expected position 3:5, got %v:%v", obj["line"], obj["column"])
+	}
+}
+
+func TestThrowCustomObject(t *testing.T) {
+	result, err, errs := parseAndEval(`let kind = null
+try {
+    throw {message: "too low", kind: "ValidationError", min: 10}
+} catch (e) {
+    kind = e.kind + ":" + e.message + ":" + e.value.min
+}
+kind`, nil)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if result != "ValidationError:too low:10" {
+		t.Errorf("unexpected result: %v", result)
+	}
+}
+
+func TestFinallyBlock(t *testing.T) {
+	result, err, errs := parseAndEval(`let steps = ""
+try {
+    steps = steps + "try,"
+    missing()
+} catch (e) {
+    steps = steps + "catch,"
+} finally {
+    steps = steps + "finally"
+}
+let f = fn() {
+    try {
+        return "from try"
+    } finally {
+        print("cleanup")
+    }
+}
+steps + ":" + f()`, nil)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if result != "try,catch,finally:from try" {
+		t.Errorf("unexpected result: %v", result)
+	}
+}
+
+func TestUncaughtThrow(t *testing.T) {
+	_, err, errs := parseAndEval(`try {
+    throw "first"
+} finally {
+    let x = 1
+}`, nil)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if err == nil || err.Error() != "first" {
+		t.Fatalf("expected uncaught error 'first', got %v", err)
+	}
+	var rtErr *RuntimeError
+	if !errors.As(err, &rtErr) || rtErr.Kind != ErrorKindThrow {
+		t.Errorf("expected RuntimeError of kind Error, got %#v", err)
+	}
+}
+
+func TestOperationLimitNotCatchable(t *testing.T) {
+	l := lexer.New(`try {
+    while (true) { }
+} catch (e) {
+    "caught"
+}`)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	interp := New()
+	interp.SetMaxOperations(50)
+	_, err := interp.Eval(program)
+	if !errors.Is(err, ErrMaxOperationsExceeded) {
+		t.Fatalf("expected ErrMaxOperationsExceeded, got %v", err)
+	}
+}
+
+func TestMemberAssignment(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected Value
+	}{
+		{`let o = {a: 1}
+o.a = 2
+o.a`, int64(2)},
+		{`let o = {}
+o["key"] = "v"
+o.key`, "v"},
+		{`let arr = [1, 2, 3]
+arr[1] = 20
+arr[1]`, int64(20)},
+		{`let arr = [1, 2]
+arr[size(arr)] = 3
+size(arr)`, int64(3)},
+		{`let o = {items: [], meta: {count: 0}}
+o.items[0] = "first"
+o.meta.count = 1
+o.items[0] + o.meta.count`, "first1"},
+		{`let grid = [[1, 2], [3, 4]]
+grid[1][0] = 30
+grid[1][size(grid[1])] = 5
+grid[1][0] + grid[1][2]`, int64(35)},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		if result != tt.expected {
+			t.Errorf("'%s': expected %v, got %v", tt.source, tt.expected, result)
+		}
+	}
+}
+
+func TestArrayAppendAliasing(t *testing.T) {
+	// Elements are shared between aliases, an appended element only reaches
+	// the variable the append was written through
+	result, err, errs := parseAndEval(`let arr = [1, 2]
+let alias = arr
+arr[0] = 9
+arr[size(arr)] = 3
+[size(arr), size(alias), alias[0]]`, nil)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if err != nil {
+		t.Fatalf("eval error: %v", err)
+	}
+	if !valuesEqual(result, []interface{}{int64(3), int64(2), int64(9)}) {
+		t.Errorf("expected [3, 2, 9], got %v", result)
+	}
+
+	// Two aliases appending past the same length each get their own element
+	result, err, errs = parseAndEval(`let a = [1, 2, 3]
+a[3] = 4
+let b = a
+a[4] = 5
+b[4] = 9
+[a, b]`, nil)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if err != nil {
+		t.Fatalf("eval error: %v", err)
+	}
+	expected := []interface{}{
+		[]interface{}{int64(1), int64(2), int64(3), int64(4), int64(5)},
+		[]interface{}{int64(1), int64(2), int64(3), int64(4), int64(9)},
+	}
+	if !valuesEqual(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+
+	// Appending to a parameter leaves the caller's array untouched
+	result, err, errs = parseAndEval(`let items = [1]
+items[1] = 2
+fn add(list, x) {
+    list[size(list)] = x
+    return list
+}
+let other = add(items, 7)
+items[2] = 3
+[items, other]`, nil)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if err != nil {
+		t.Fatalf("eval error: %v", err)
+	}
+	expected = []interface{}{
+		[]interface{}{int64(1), int64(2), int64(3)},
+		[]interface{}{int64(1), int64(2), int64(7)},
+	}
+	if !valuesEqual(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+}
+
+func TestMemberAssignmentErrors(t *testing.T) {
+	tests := []string{
+		"let arr = [1]\narr[5] = 2",
+		"let arr = [1]\narr[\"x\"] = 2",
+		"let o = null\no.a = 1",
+		"fn items() { return [1] }\nitems()[1] = 2",
+	}
+
+	for _, source := range tests {
+		_, err, errs := parseAndEval(source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", source, errs)
+		}
+		if err == nil {
+			t.Errorf("'%s': expected error", source)
+		}
+	}
+
+	_, _, errs := parseAndEval("f() = 1", nil)
+	if len(errs) == 0 {
+		t.Errorf("expected parse error for invalid assignment target")
+	}
+}
+
+func TestCompoundAssignment(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected Value
+	}{
+		{"let x = 10\nx += 5\nx", int64(15)},
+		{"let x = 10\nx -= 4\nx", int64(6)},
+		{"let x = 10\nx *= 3\nx", int64(30)},
+		{"let x = 10\nx /= 4\nx", float64(2.5)},
+		{"let x = 10\nx %= 4\nx", int64(2)},
+		{"let s = \"a\"\ns += \"b\"\ns", "ab"},
+		{"let x = 1\nx++\nx++\nx", int64(3)},
+		{"let x = 1\nx--\nx", int64(0)},
+		{"let x = 1\n++x\nx", int64(2)},
+		{"let o = {n: 1}\no.n += 2\no.n++\no.n", int64(4)},
+		{"let arr = [1, 2]\narr[1] *= 10\n--arr[0]\narr[0] + arr[1]", int64(20)},
+
+		// Between operands ++ and -- are two signs, as before updates existed
+		{"5--3", int64(8)},
+		{"let a = 5\nlet b = 3\na--b", int64(8)},
+		{"let a = 5\nlet y = a - --a\ny", int64(0)},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		if result != tt.expected {
+			t.Errorf("'%s': expected %v, got %v", tt.source, tt.expected, result)
+		}
+	}
+}
+
+func TestCompoundAssignmentEvaluatesIndexOnce(t *testing.T) {
+	result, err, errs := parseAndEval(`let calls = [0]
+let arr = [10, 20]
+let next = fn() {
+    calls[0] += 1
+    return 1
+}
+arr[next()] += 5
+calls[0] + ":" + arr[1]`, nil)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if result != "1:25" {
+		t.Errorf("expected '1:25', got %v", result)
+	}
+}
+
+func TestCompoundAssignmentUndefined(t *testing.T) {
+	_, err, errs := parseAndEval("missing += 1", nil)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if err == nil {
+		t.Fatal("expected error for compound assignment to undefined variable")
+	}
+}
+
+func TestConditionalExpression(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected Value
+	}{
+		{`true ? "yes" : "no"`, "yes"},
+		{`false ? "yes" : "no"`, "no"},
+		{`let x = 5
+x > 3 ? "big" : "small"`, "big"},
+		{`let x = 2
+x > 3 ? "big" : x > 1 ? "medium" : "small"`, "medium"},
+		{`null ?: false ? "a" : "b"`, "b"},
+		{`true || false ? 1 : 2`, int64(1)},
+		{`let o = {label: 1 > 2 ? "a" : "b"}
+o.label`, "b"},
+		{`1 + 1 == 2 ? 10 + 5 : 0`, int64(15)},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		if result != tt.expected {
+			t.Errorf("'%s': expected %v, got %v", tt.source, tt.expected, result)
+		}
+	}
+}
+
+func TestConditionalShortCircuit(t *testing.T) {
+	result, err, errs := parseAndEval(`true ? "ok" : missing()`, nil)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "ok" {
+		t.Errorf("expected 'ok', got %v", result)
+	}
+}
+
+func TestDestructuringDeclaration(t *testing.T) {
+	vars := map[string]interface{}{
+		"user": map[string]interface{}{
+			"name": "Alice",
+			"tier": "gold",
+			"address": map[string]interface{}{
+				"city": "Paris",
+			},
+		},
+		"pair": []interface{}{"x", int64(2)},
+	}
+
+	tests := []struct {
+		source   string
+		expected Value
+	}{
+		{"let {name, tier} = user\nname + \"/\" + tier", "Alice/gold"},
+		{"let {tier: level} = user\nlevel", "gold"},
+		{"let {address: {city}} = user\ncity", "Paris"},
+		{"let {email = \"none\"} = user\nemail", "none"},
+		{"let {address: {zip = \"00000\"}} = user\nzip", "00000"},
+		{"let [a, b] = pair\na + b", "x2"},
+		{"let [a, b, c = 3] = pair\nc", int64(3)},
+		{"let [[x, y], {k}] = [[1, 2], {k: 3}]\nx + y + k", int64(6)},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(tt.source, vars)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		if result != tt.expected {
+			t.Errorf("'%s': expected %v, got %v", tt.source, tt.expected, result)
+		}
+	}
+}
+
+func TestDestructuringForLoop(t *testing.T) {
+	result, err, errs := parseAndEval(`let total = 0
+let names = ""
+for ({name, qty = 1} in items) {
+    names += name
+    total += qty
+}
+for ([k, v] in [["a", 10], ["b", 20]]) {
+    total += v
+}
+names + total`, map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"name": "A", "qty": float64(2)},
+			map[string]interface{}{"name": "B"},
+		},
+	})
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if result != "AB33.0" {
+		t.Errorf("expected 'AB33.0', got %v", result)
+	}
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	tests := []string{
+		"let {a} = null",
+		"let [a] = {x: 1}",
+	}
+
+	for _, source := range tests {
+		_, err, errs := parseAndEval(source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", source, errs)
+		}
+		if err == nil {
+			t.Errorf("'%s': expected error", source)
+		}
+	}
+}
+
+func TestSpread(t *testing.T) {
+	vars := map[string]interface{}{
+		"defaults":  map[string]interface{}{"theme": "light", "lang": "fr"},
+		"overrides": map[string]interface{}{"theme": "dark"},
+	}
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{"let o = {...defaults, ...overrides}\no.theme + o.lang", "darkfr"},
+		{"let o = {theme: \"x\", ...defaults}\no.theme", "light"},
+		{"let o = {...defaults, theme: \"x\"}\no.theme", "x"},
+		{"let o = {...null, a: 1}\no.a", int64(1)},
+		{"let a = [1, 2]\nlet b = [...a, 3, ...a]\nsize(b)", int64(5)},
+		{"let nums = [3, 9, 4]\nmax(...nums)", int64(9)},
+		{"let nums = [3, 9, 4]\nmin(1, ...nums)", int64(1)},
+		{"let add = fn(a, b) { return a + b }\nadd(...[2, 5])", int64(7)},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(tt.source, vars)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		if result != tt.expected {
+			t.Errorf("'%s': expected %v, got %v", tt.source, tt.expected, result)
+		}
+	}
+}
+
+func TestRestParameters(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{"let f = fn(first, ...others) { return size(others) }\nf(1, 2, 3)", int64(2)},
+		{"let f = fn(first, ...others) { return size(others) }\nf(1)", int64(0)},
+		{"let f = fn(...all) { return all[0] + all[1] }\nf(4, 5)", int64(9)},
+		{"let f = fn(label, ...nums) { return label + max(...nums) }\nf(\"max=\", 1, 8, 2)", "max=8"},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		if result != tt.expected {
+			t.Errorf("'%s': expected %v, got %v", tt.source, tt.expected, result)
+		}
+	}
+}
+
+func TestSpreadErrors(t *testing.T) {
+	tests := []string{
+		"[...5]",
+		"let o = {...[1, 2]}",
+		"max(...\"abc\")",
+	}
+
+	for _, source := range tests {
+		_, err, errs := parseAndEval(source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", source, errs)
+		}
+		if err == nil {
+			t.Errorf("'%s': expected error", source)
+		}
+	}
+}
+
+func TestDefaultParameters(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{"let f = fn(x, y = 10) { return x + y }\nf(1)", int64(11)},
+		{"let f = fn(x, y = 10) { return x + y }\nf(1, 2)", int64(3)},
+		{"let f = fn(x, y = x * 2) { return y }\nf(4)", int64(8)},
+		{"let f = fn(x, y = 10) { return y }\nf(1, null)", nil},
+		{"let f = fn(x, y) { return y }\nf(1)", nil},
+		{"let f = fn(a, b) { return a }\nf(1, 2, 3)", int64(1)},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		if result != tt.expected {
+			t.Errorf("'%s': expected %v, got %v", tt.source, tt.expected, result)
+		}
+	}
+}
+
+func TestFunctionDeclarations(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{"fn double(x) { return x * 2 }\ndouble(21)", int64(42)},
+		{"let r = triple(3)\nfn triple(x) { return x * 3 }\nr", int64(9)},
+		{"fn fact(n) { if (n <= 1) { return 1 }\nreturn n * fact(n - 1) }\nfact(5)", int64(120)},
+		{`fn isEven(n) { if (n == 0) { return true }
+return isOdd(n - 1) }
+fn isOdd(n) { if (n == 0) { return false }
+return isEven(n - 1) }
+isEven(10)`, true},
+		{"fn outer() { return inner()\nfn inner() { return \"in\" } }\nouter()", "in"},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		if result != tt.expected {
+			t.Errorf("'%s': expected %v, got %v", tt.source, tt.expected, result)
+		}
+	}
+}
+
+func TestStrictArityErrors(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"fn f(a, b) { return a }\nf(1, 2, 3)", "function 'f' expects 2 arguments, got 3"},
+		{"fn f(a, b = 1) { return a }\nf()", "function 'f' expects 1 to 2 arguments, got 0"},
+		{"fn f(a, ...rest) { return a }\nf()", "function 'f' expects at least 1 argument, got 0"},
+		{"let f = fn(a) { return a }\nf()", "function expects 1 argument, got 0"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.source)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, p.Errors())
+		}
+		interp := New()
+		interp.SetStrictArity(true)
+		_, err := interp.Eval(program)
+		if err == nil {
+			t.Fatalf("'%s': expected error", tt.source)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("'%s': expected %q, got %q", tt.source, tt.expected, err.Error())
+		}
+		var rtErr *RuntimeError
+		if !errors.As(err, &rtErr) || rtErr.Kind != ErrorKindType || rtErr.Line != 2 {
+			t.Errorf("'%s': expected TypeError at line 2, got %#v", tt.source, err)
+		}
+	}
+}
+
+func TestArrowFunctions(t *testing.T) {
+	vars := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"name": "a", "price": float64(5)},
+			map[string]interface{}{"name": "b", "price": float64(20)},
+			map[string]interface{}{"name": "c", "price": float64(12)},
+		},
+	}
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{"let double = x => x * 2\ndouble(21)", int64(42)},
+		{"let add = (a, b) => a + b\nadd(2, 3)", int64(5)},
+		{"let answer = () => 42\nanswer()", int64(42)},
+		{"let f = (a, b = 10) => a + b\nf(1)", int64(11)},
+		{"let f = (first, ...rest) => size(rest)\nf(1, 2, 3)", int64(2)},
+		{"let f = (x) => {\n    let y = x + 1\n    return y * 2\n}\nf(2)", int64(6)},
+		{"let adder = x => y => x + y\nadder(3)(4)", int64(7)},
+		{"let total = reduce(map(items, x => x.price * 2), (acc, p) => acc + p, 0)\ntotal", float64(74)},
+		{"size(filter(items, item => item.price > 10))", int64(2)},
+		{"find(items, item => item.price == 12).name", "c"},
+		{"findIndex(items, item => item.name == \"b\")", int64(1)},
+		{"let pick = x => x > 10 ? \"big\" : \"small\"\npick(11)", "big"},
+		{"(2 + 3) * 4", int64(20)},
+		{"let a = 2\n(a) * 3", int64(6)},
+		{"let f = (a = (1 + 2) * (3)) => a\nf()", int64(9)},
+		{"let a = 2\n((a) + (a)) * (a)", int64(8)},
+		{strings.Repeat("(", 2000) + "1" + strings.Repeat(")", 2000), int64(1)},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(tt.source, vars)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		if result != tt.expected {
+			t.Errorf("'%s': expected %v, got %v", tt.source, tt.expected, result)
+		}
+	}
+}
+
+func TestConstDeclarations(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{"const rate = 0.2\nrate * 100", float64(20)},
+		{"const {name, tier = \"basic\"} = {name: \"A\"}\nname + tier", "Abasic"},
+		{"const cfg = {debug: false}\ncfg.debug = true\ncfg.debug", true},
+		{"const x = 1\nlet f = fn() { let x = 2\nreturn x }\nf() + x", int64(3)},
+		{"const x = 1\nlet f = (x) => x * 10\nf(5)", int64(50)},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		if result != tt.expected {
+			t.Errorf("'%s': expected %v, got %v", tt.source, tt.expected, result)
+		}
+	}
+}
+
+func TestConstParseErrors(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"const x = 1\nx = 2", "line 2, col 1: cannot assign to constant 'x'"},
+		{"const x = 1\nx += 2", "line 2, col 1: cannot assign to constant 'x'"},
+		{"const x = 1\nx++", "line 2, col 1: cannot assign to constant 'x'"},
+		{"const x = 1\nlet f = fn() { x = 2 }", "line 2, col 16: cannot assign to constant 'x'"},
+		{"const x = 1\nlet x = 2", "line 2, col 5: cannot redeclare constant 'x'"},
+		{"let x = 1\nconst x = 2", "line 2, col 7: cannot declare constant 'x': name already declared in this scope"},
+		{"const {a, b} = {a: 1, b: 2}\nb = 3", "line 2, col 1: cannot assign to constant 'b'"},
+	}
+
+	for _, tt := range tests {
+		_, _, errs := parseAndEval(tt.source, nil)
+		if len(errs) == 0 {
+			t.Fatalf("'%s': expected parse error", tt.source)
+		}
+		if errs[0] != tt.expected {
+			t.Errorf("'%s': expected %q, got %q", tt.source, tt.expected, errs[0])
+		}
+	}
+}
+
+func TestHostConstants(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"user = null", "cannot assign to constant 'user'"},
+		{"user += \"x\"", "cannot assign to constant 'user'"},
+		{"let user = \"bob\"", "cannot redeclare constant 'user'"},
+		{"fn user() { }", "cannot redeclare constant 'user'"},
+		{"let f = fn() { user = \"bob\" }\nf()", "cannot assign to constant 'user'"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.source)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, p.Errors())
+		}
+		interp := New()
+		interp.SetConstant("user", "alice")
+		_, err := interp.Eval(program)
+		if err == nil {
+			t.Fatalf("'%s': expected error", tt.source)
+		}
+		var rtErr *RuntimeError
+		if !errors.As(err, &rtErr) || rtErr.Kind != ErrorKindType {
+			t.Errorf("'%s': expected TypeError, got %#v", tt.source, err)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("'%s': expected %q, got %q", tt.source, tt.expected, err.Error())
+		}
+	}
+
+	// Shadowing inside a function is allowed
+	l := lexer.New("let f = fn() { let user = \"bob\"\nreturn user }\nf() + user")
+	p := parser.New(l)
+	interp := New()
+	interp.SetConstant("user", "alice")
+	result, err := interp.Eval(p.ParseProgram())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "bobalice" {
+		t.Errorf("expected 'bobalice', got %v", result)
+	}
+}
+
+func TestBlockScoping(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{"let x = 1\nif (true) { let x = 2 }\nx", int64(1)},
+		{"let x = 1\nif (true) { x = 2 }\nx", int64(2)},
+		{"let count = 0\nfor (n in [1, 2, 3]) { count += n }\ncount", int64(6)},
+		{"let n = \"outer\"\nfor (n in [1, 2]) { }\nn", "outer"},
+		{"let fns = []\nfor (n in [1, 2, 3]) { fns[size(fns)] = () => n }\nfns[0]() + fns[2]()", int64(4)},
+		{"let fns = []\nlet i = 0\nwhile (i < 3) {\n    let v = i * 10\n    fns[size(fns)] = () => v\n    i++\n}\nfns[1]()", int64(10)},
+		{"let total = 0\nlet add = fn(n) { total = total + n }\nadd(5)\nadd(2)\ntotal", int64(7)},
+		{"if (true) { z = 3 }\nz", int64(3)},
+		{"let e = \"none\"\ntry { throw \"boom\" } catch (e) { }\ne", "none"},
+		{"let r = match (1) { 1 -> { let tmp = 5\n tmp } else -> 0 }\nr", int64(5)},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		if result != tt.expected {
+			t.Errorf("'%s': expected %v, got %v", tt.source, tt.expected, result)
+		}
+	}
+}
+
+func TestBlockScopingUndefined(t *testing.T) {
+	tests := []string{
+		"if (true) { let y = 1 }\ny",
+		"for (item in [1, 2]) { }\nitem",
+		"while (true) { let w = 1\nbreak }\nw",
+		"try { throw 1 } catch (err) { }\nerr",
+		"if (true) { fn helper() { return 1 } }\nhelper()",
+	}
+
+	for _, source := range tests {
+		_, err, errs := parseAndEval(source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", source, errs)
+		}
+		var rtErr *RuntimeError
+		if !errors.As(err, &rtErr) || rtErr.Kind != ErrorKindReference {
+			t.Errorf("'%s': expected ReferenceError, got %v", source, err)
+		}
+	}
+}
+
+func TestLegacyScoping(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{"if (true) { let y = 1 }\ny", int64(1)},
+		{"for (item in [1, 2]) { }\nitem", int64(2)},
+		{"let fns = []\nfor (n in [1, 2, 3]) { fns[size(fns)] = () => n }\nfns[0]()", int64(3)},
+		{"let total = 1\nlet f = fn() { total = 5\nreturn total }\nf() + total", int64(6)},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.source)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, p.Errors())
+		}
+		interp := New()
+		interp.SetLegacyScoping(true)
+		result, err := interp.Eval(program)
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		if result != tt.expected {
+			t.Errorf("'%s': expected %v, got %v", tt.source, tt.expected, result)
+		}
+	}
+}
+
+func TestIntegers(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected Value
+	}{
+		{"7", int64(7)},
+		{"7.0", float64(7)},
+		{"7 / 2", 3.5},
+		{"8 / 2", int64(4)},
+		{"7 % 3", int64(1)},
+		{"-7 % 3", int64(-1)},
+		{"2 * 2.5", float64(5)},
+		{"1 + 0.5", 1.5},
+		{"9223372036854775807", int64(9223372036854775807)},
+		{"9223372036854775807 - 1 + 1", int64(9223372036854775807)},
+		{"9223372036854775807 + 1", float64(9223372036854775808)},
+		{"-9223372036854775807 - 2", float64(-9223372036854775809)},
+		{"4611686018427387904 * 2", float64(9223372036854775808)},
+		{"-4611686018427387904 * 2", int64(-9223372036854775808)},
+		{"9223372036854775808", float64(9223372036854775808)},
+		{"9007199254740993 == 9007199254740992", false},
+		{"9007199254740993 > 9007199254740992", true},
+		{"1 == 1.0", true},
+		{"2 != 2.0", false},
+		{"let n = 5\nn++\nn", int64(6)},
+		{"let n = 10\nn /= 4\nn", 2.5},
+		{`toString(3) + "/" + toString(3.5)`, "3/3.5"},
+		{`toString(3.0) + " " + float(3) + " " + 6 / 2.0`, "3.0 3.0 3.0"},
+		{`"" + 1e20 + " " + [1.0, 2]`, "100000000000000000000.0 [1.0 2]"},
+		{`"id: " + 9007199254740993`, "id: 9007199254740993"},
+		{"int(3.9)", int64(3)},
+		{"int(-3.9)", int64(-3)},
+		{`int("9007199254740993")`, int64(9007199254740993)},
+		{`int("2.5")`, int64(2)},
+		{"float(3)", float64(3)},
+		{`float("2.5")`, 2.5},
+		{"let last = 0\nfor (n in 1..3) { last = n }\nlast", int64(3)},
+		{"let last = 0\nfor (n in 0.5..2) { last = n }\nlast", 1.5},
+		{"let arr = [10, 20, 30]\narr[1]", int64(20)},
+		{"let arr = [10, 20, 30]\narr[2.0]", int64(30)},
+		{`match (2) { 1..3 -> "low", else -> "high" }`, "low"},
+		{`match (2.0) { 2 -> "two", else -> "other" }`, "two"},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		if result != tt.expected {
+			t.Errorf("'%s': expected %v (%T), got %v (%T)", tt.source, tt.expected, tt.expected, result, result)
+		}
+	}
+
+	errorTests := []struct {
+		source   string
+		expected string
+	}{
+		{"1 / 0", "division by zero"},
+		{"1 % 0", "modulo by zero"},
+		{`int("abc")`, "cannot convert 'abc' to int"},
+		{`int(float("1e300"))`, "out of range"},
+	}
+
+	for _, tt := range errorTests {
+		_, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("'%s': expected error containing %q, got %v", tt.source, tt.expected, err)
+		}
+	}
+}
+
+func TestComparisonOrdering(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected Value
+	}{
+		{`"apple" < "banana"`, true},
+		{`"Zebra" < "apple"`, true},
+		{`"abc" >= "abc"`, true},
+		{`"2024-01-15" < "2024-02-01"`, true},
+		{`"10" < "9"`, true},
+		{`false < true`, true},
+		{`true <= false`, false},
+		{"let name = \"Bob\"\nname < \"M\" ? \"A-L\" : \"M-Z\"", "A-L"},
+		{`max("pear", "apple", "fig")`, "pear"},
+		{`sort(["b", "C", "a"])`, []interface{}{"C", "a", "b"}},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		if !valuesEqual(result, tt.expected) {
+			t.Errorf("'%s': expected %v, got %v", tt.source, tt.expected, result)
+		}
+	}
+
+	errorTests := []struct {
+		source   string
+		expected string
+	}{
+		{`"a" < 1`, "cannot compare string and int64"},
+		{`null < 1`, "cannot compare null and int64"},
+		{`[1] < [2]`, "cannot compare []interface {} and []interface {}"},
+		{`min("a", 1)`, "min: cannot compare int64 and string"},
+	}
+
+	for _, tt := range errorTests {
+		_, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("'%s': expected error containing %q, got %v", tt.source, tt.expected, err)
+		}
+	}
+}
+
+func TestDeepEquality(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected Value
+	}{
+		{"[1, 2, 3] == [1, 2, 3]", true},
+		{"[1, 2, 3] == [1, 2]", false},
+		{"[1, [2, 3]] == [1.0, [2, 3.0]]", true},
+		{"[1, 2] != [2, 1]", true},
+		{`{a: 1, b: {c: [true]}} == {b: {c: [true]}, a: 1}`, true},
+		{`{a: 1} == {a: 1, b: 2}`, false},
+		{`{a: null} == {b: null}`, false},
+		{`{a: 1} == [1]`, false},
+		{`[] == {}`, false},
+		{`[0.1d, 2] == [0.1, 2.0]`, true},
+		{"let a = {}\na.self = a\nlet b = {}\nb.self = b\na == b", true},
+		{"let a = [1]\na[1] = a\nlet b = [1]\nb[1] = b\na == b", true},
+		{"let f = x => x\nf == f", true},
+		{"(x => x) == (x => x)", false},
+		{`match ([1, 2]) { [1, 2] -> "pair", else -> "other" }`, "pair"},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		if result != tt.expected {
+			t.Errorf("'%s': expected %v, got %v", tt.source, tt.expected, result)
+		}
+	}
+}
+
+func TestInOperator(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected Value
+	}{
+		{"2 in [1, 2, 3]", true},
+		{"4 in [1, 2, 3]", false},
+		{"2.0 in [1, 2, 3]", true},
+		{`[1, 2] in [[1, 2], [3]]`, true},
+		{`{id: 1} in [{id: 1}]`, true},
+		{`"a" in {a: null}`, true},
+		{`"b" in {a: 1}`, false},
+		{"5 in 1..10", true},
+		{"10 in 1..<10", false},
+		{"1.5 in 1..3", false},
+		{"2.0 in 1..3", true},
+		{"2d in 1..3", true},
+		{"1.5 in 1.0..3", true},
+		{"match (2.5) { 1..3 -> \"yes\" else -> \"no\" }", "no"},
+		{`"x" in null`, false},
+		{`!("x" in ["x"])`, false},
+		{`1 + 1 in [2] && true`, true},
+		{"let n = 0\nfor (x in [1, 2, 3]) { if (x in [2, 3]) { n++ } }\nn", int64(2)},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		if result != tt.expected {
+			t.Errorf("'%s': expected %v (%T), got %v (%T)", tt.source, tt.expected, tt.expected, result, result)
+		}
+	}
+
+	errorTests := []struct {
+		source   string
+		expected string
+	}{
+		{`1 in "abc"`, "cannot use 'in' with string"},
+		{`1 in {a: 1}`, "object keys are strings, got int64 on the left of 'in'"},
+	}
+
+	for _, tt := range errorTests {
+		_, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("'%s': expected error containing %q, got %v", tt.source, tt.expected, err)
+		}
+	}
+}
+
+func TestBitwiseAndPowerOperators(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected Value
+	}{
+		{"6 & 3", int64(2)},
+		{"6 | 3", int64(7)},
+		{"6 ^ 3", int64(5)},
+		{"~5", int64(-6)},
+		{"1 << 4", int64(16)},
+		{"-16 >> 2", int64(-4)},
+		{"1 << 63", int64(math.MinInt64)},
+		{"-1 >> 63", int64(-1)},
+		{"8.0 & 12", int64(8)},
+		{"4d | 1", int64(5)},
+		{"let READ = 1\nlet WRITE = 2\nlet perms = READ | WRITE\n(perms & WRITE) != 0", true},
+		{"let flags = 0\nflags = flags | 1 << 3\nflags", int64(8)},
+		{"1 | 6 & 3", int64(3)}, // & binds tighter than |
+		{"1 + 1 << 2", int64(8)},
+		{"5 & 3 ^ 1 | 8", int64(8)},
+		{"2 ** 10", int64(1024)},
+		{"2 ** 3 ** 2", int64(512)},
+		{"-2 ** 2", int64(-4)},
+		{"(-2) ** 3", int64(-8)},
+		{"2 ** -1", 0.5},
+		{"2 ** 0.5 == sqrt(2)", true},
+		{"2 * 3 ** 2", int64(18)},
+		{"2 ** 63", float64(1 << 63)},
+		{"(-2) ** 63", int64(math.MinInt64)},
+		{"1.05d ** 2 == 1.1025d", true},
+		{"toString(2d ** -2)", "0.25"},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		if result != tt.expected {
+			t.Errorf("'%s': expected %v (%T), got %v (%T)", tt.source, tt.expected, tt.expected, result, result)
+		}
+	}
+
+	errorTests := []struct {
+		source   string
+		expected string
+	}{
+		{"2.5 & 1", "operator & requires integer operands, got 2.5"},
+		{`1 | "a"`, "operator | requires integer operands, got string"},
+		{"~1.5", "operator ~ requires integer operands, got 1.5"},
+		{"1 << -1", "negative shift count"},
+		{"1 << 64", "shift count 64 out of range"},
+		{"-1 >> 100", "shift count 100 out of range"},
+		{`"a" ** 2`, "cannot perform ** on string and int64"},
+		{"0d ** -1", "division by zero"},
+		{"0 ** -1", "division by zero"},
+		{"0.0 ** -0.5", "division by zero"},
+		{"2d ** 100000", "out of range"},
+	}
+
+	for _, tt := range errorTests {
+		_, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("'%s': expected error containing %q, got %v", tt.source, tt.expected, err)
+		}
+	}
+}
+
+func TestDecimals(t *testing.T) {
+	// Decimal results are compared through their plain-notation string
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"19.99d", "19.99"},
+		{"0.1d + 0.2d", "0.3"},
+		{"0.1d + 0.2", "0.3"},
+		{"19.99d * 3", "59.97"},
+		{"100d - 0.01d", "99.99"},
+		{"10d / 4", "2.5"},
+		{"1d / 3", "0.33333333333333333333"},
+		{"1d / 3 * 3", "1"},
+		{"7.5d % 2", "1.5"},
+		{"-7.5d % 2", "-1.5"},
+		{"-19.99d", "-19.99"},
+		{`decimal("12.345")`, "12.345"},
+		{"decimal(0.1) + decimal(0.2)", "0.3"},
+		{"let total = 0d\nfor (p in [0.1d, 0.2d, 0.3d]) { total += p }\ntotal", "0.6"},
+		{"round(2.345d, 2)", "2.35"},
+		{`round(2.345d, 2, "half-even")`, "2.34"},
+		{`round(-2.345d, 2, "down")`, "-2.34"},
+		{"floor(-2.5d)", "-3"},
+		{"abs(-1.25d)", "1.25"},
+		{"max(1d, 2.5d, 1.5)", "2.5"},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		d, ok := result.(*big.Rat)
+		if !ok {
+			t.Fatalf("'%s': expected a decimal, got %v (%T)", tt.source, result, result)
+		}
+		if got := decimal.Format(d); got != tt.expected {
+			t.Errorf("'%s': expected %s, got %s", tt.source, tt.expected, got)
+		}
+	}
+
+	valueTests := []struct {
+		source   string
+		expected Value
+	}{
+		{"0.1d + 0.2d == 0.3d", true},
+		{"0.1d + 0.2d == 0.3", true},
+		{"0.1 + 0.2 == 0.3", false},
+		{"1.5d == 1.5d", true},
+		{"2d == 2", true},
+		{"19.99d < 20", true},
+		{"0.1d >= 0.10d", true},
+		{"1.5d != 1.25d", true},
+		{`"Total: " + 19.90d`, "Total: 19.9"},
+		{"toString(1d / 4)", "0.25"},
+		{"typeOf(1.5d)", "decimal"},
+		{"isNumber(1.5d)", true},
+		{"int(19.99d)", int64(19)},
+		{"float(1.5d)", 1.5},
+		{`match (2.50d) { 2.5 -> "match", else -> "other" }`, "match"},
+		{`jsonStringify({price: 19.99d, items: [0.5d]})`, `{"items":[0.5],"price":19.99}`},
+	}
+
+	for _, tt := range valueTests {
+		result, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		if result != tt.expected {
+			t.Errorf("'%s': expected %v (%T), got %v (%T)", tt.source, tt.expected, tt.expected, result, result)
+		}
+	}
+
+	errorTests := []struct {
+		source   string
+		expected string
+	}{
+		{"1d / 0", "division by zero"},
+		{"1d % 0d", "modulo by zero"},
+		{`decimal("abc")`, "cannot convert 'abc' to decimal"},
+		{`decimal("1/3")`, "cannot convert '1/3' to decimal"},
+		{`round(1.5d, 0, "nearest")`, "unknown rounding mode 'nearest'"},
+		{`1.5d - "a"`, "cannot perform -"},
+	}
+
+	for _, tt := range errorTests {
+		_, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("'%s': expected error containing %q, got %v", tt.source, tt.expected, err)
+		}
+	}
+}
+
+func TestRanges(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{"let sum = 0\nfor (n in 1..5) { sum += n }\nsum", int64(15)},
+		{"let sum = 0\nfor (n in 0..<5) { sum += n }\nsum", int64(10)},
+		{"let count = 0\nfor (n in 5..1) { count++ }\ncount", int64(0)},
+		{"let n = 3\nlet total = 0\nfor (i in 1..n * 2) { total += i }\ntotal", int64(21)},
+		{"let r = 1..<3\ntoString(r)", "1..<3"},
+		{"1..3 == 1..3", true},
+		{"let small = 1..9\nmatch (4) { small -> \"small\" else -> \"big\" }", "small"},
+		{"match (10) { 1..<10 -> \"digit\" else -> \"more\" }", "more"},
+		{"let last = 0\nfor (n in 1..1000000000) { last = n\nif (n == 3) { break } }\nlast", int64(3)},
+		// Integer bounds stay exact past 2^53 and near the int64 limits
+		{"let last = 0\nfor (n in 9007199254740993..9007199254740995) { last = n }\nlast", int64(9007199254740995)},
+		{"toString(9007199254740993..<9007199254740995)", "9007199254740993..<9007199254740995"},
+		{"9007199254740993 in 9007199254740992..9007199254740992", false},
+		{"let count = 0\nfor (n in 9223372036854775806..9223372036854775807) { count++ }\ncount", int64(2)},
+		{"let first = 0\nfor (n in -9223372036854775807..9223372036854775807) { first = n\nbreak }\nfirst", int64(-9223372036854775807)},
+		// Outside for, a range reads like the array of its values
+		{"toString(map(1..3, x => x * 2))", "[2 4 6]"},
+		{"reduce(0..<4, (acc, n) => acc + n, 0)", int64(6)},
+		{"size(1..5) + size(1..<1) + size(0.5..2)", int64(7)},
+		{"toString([0, ...1..3])", "[0 1 2 3]"},
+		{"let [a, b] = 10..20\na + b", int64(21)},
+		{"size(1..9000000000)", int64(9000000000)},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		if result != tt.expected {
+			t.Errorf("'%s': expected %v, got %v", tt.source, tt.expected, result)
+		}
+	}
+
+	_, err, _ := parseAndEval("for (n in 1..\"x\") { }", nil)
+	if err == nil || err.Error() != "range bounds must be numbers, got int64 and string" {
+		t.Errorf("expected range bounds error, got %v", err)
+	}
+
+	errorTests := []struct {
+		source   string
+		expected string
+	}{
+		{"map(1..9000000000, x => x)", "range 1..9000000000 has more than 1000000 values to expand into an array"},
+		{"[...1..9000000000]", "range 1..9000000000 has more than 1000000 values to expand into an array"},
+		{"filter(5, x => true)", "filter requires an array or a range, got int64"},
+	}
+	for _, tt := range errorTests {
+		_, err, errs := parseAndEval(tt.source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("'%s': expected error containing %q, got %v", tt.source, tt.expected, err)
+		}
+	}
+}
+
+func TestIndexedAndObjectIteration(t *testing.T) {
+	vars := map[string]interface{}{
+		"prices": map[string]interface{}{"b": float64(2), "a": float64(1), "c": float64(3)},
+	}
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{"let out = \"\"\nfor (i, item in [\"x\", \"y\"]) { out += i + item }\nout", "0x1y"},
+		{"let out = \"\"\nfor (i, n in 10..12) { out += i + \":\" + n + \" \" }\nout", "0:10 1:11 2:12 "},
+		{"let keys = \"\"\nfor (key in prices) { keys += key }\nkeys", "abc"},
+		{"let out = \"\"\nfor (key, value in prices) { out += key + value }\nout", "a1.0b2.0c3.0"},
+		{"let out = \"\"\nfor (i, {name} in [{name: \"A\"}, {name: \"B\"}]) { out += i + name }\nout", "0A1B"},
+	}
+
+	for _, tt := range tests {
+		result, err, errs := parseAndEval(tt.source, vars)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		if result != tt.expected {
+			t.Errorf("'%s': expected %v, got %v", tt.source, tt.expected, result)
+		}
+	}
+
+	_, _, errs := parseAndEval("for ([a, b], item in []) { }", nil)
+	if len(errs) == 0 {
+		t.Error("expected a parse error for a destructuring index variable")
+	}
+}
+
+func TestComments(t *testing.T) {
+	source := `/*
+ * License header
+ */
+/// Price including VAT.
+/// The rate defaults to 20%.
+fn ttc(amount, rate = 0.2) {
+    /* disabled: /* nested */ return amount */
+    return amount * (1 + rate)
+}
+
+/// Base price.
+const base = 100
+let total = ttc(base) /* trailing */
+/// Exported helper.
+export let half = base / 2
+total`
+
+	l := lexer.New(source)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parse errors: %v", p.Errors())
+	}
+
+	fnDecl, ok := program.Statements[0].(*ast.FunctionDecl)
+	if !ok || fnDecl.Doc != "Price including VAT.\nThe rate defaults to 20%." {
+		t.Errorf("expected documented function, got %#v", program.Statements[0])
+	}
+	if decl, ok := program.Statements[1].(*ast.VarDecl); !ok || decl.Doc != "Base price." {
+		t.Errorf("expected documented const, got %#v", program.Statements[1])
+	}
+	if decl, ok := program.Statements[2].(*ast.VarDecl); !ok || decl.Doc != "" {
+		t.Errorf("expected undocumented let, got %#v", program.Statements[2])
+	}
+	export, ok := program.Statements[3].(*ast.ExportStatement)
+	if !ok || export.Declaration.(*ast.VarDecl).Doc != "Exported helper." {
+		t.Errorf("expected documented export, got %#v", program.Statements[3])
+	}
+
+	result, err := New().Eval(program)
+	if err != nil {
+		t.Fatalf("eval error: %v", err)
+	}
+	if !valuesEqual(result, 120.0) {
+		t.Errorf("expected 120, got %v", result)
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected Value
+	}{
+		{"1e6", 1e6},
+		{"2.5E-3", 0.0025},
+		{"1e300 * 10", 1e301},
+		{"6.02e+23", 6.02e23},
+		{"0xFF", int64(255)},
+		{"0Xff + 1", int64(256)},
+		{"0o17", int64(15)},
+		{"0b1010", int64(10)},
+		{"0b1111_0000", int64(240)},
+		{"0x7FFF_FFFF_FFFF_FFFF", int64(math.MaxInt64)},
+		{"1_000_000", int64(1000000)},
+		{"1_000.000_1", 1000.0001},
+		{"0x1d", int64(29)},
+		{"1..0x3", []interface{}{int64(1), int64(2), int64(3)}},
+	}
+
+	for _, tt := range tests {
+		source := tt.source
+		if strings.Contains(source, "..") {
+			source = "let out = []\nfor (n in " + source + ") { out[size(out)] = n }\nout"
+		}
+		result, err, errs := parseAndEval(source, nil)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors for '%s': %v", tt.source, errs)
+		}
+		if err != nil {
+			t.Fatalf("eval error for '%s': %v", tt.source, err)
+		}
+		if !valuesEqual(result, tt.expected) {
+			t.Errorf("'%s': expected %v (%T), got %v (%T)", tt.source, tt.expected, tt.expected, result, result)
+		}
+	}
+
+	sum, _, errs := parseAndEval("1.5e3d + 0.25d", nil)
+	if len(errs) > 0 || decimal.Format(sum.(*big.Rat)) != "1500.25" {
+		t.Errorf("expected decimal 1500.25, got %v %v", sum, errs)
+	}
+}
+
+func TestMalformedNumberLiterals(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"1e", "line 1, col 1: malformed number 1e: exponent has no digits"},
+		{"let x = 2.5e+", "line 1, col 9: malformed number 2.5e+: exponent has no digits"},
+		{"0x", "line 1, col 1: malformed number 0x: hexadecimal literal has no digits"},
+		{"0b102", "line 1, col 1: malformed number 0b102: invalid digit '2' in binary literal"},
+		{"0o8", "line 1, col 1: malformed number 0o8: invalid digit '8' in octal literal"},
+		{"0xFG", "line 1, col 1: malformed number 0xFG: invalid digit 'G' in hexadecimal literal"},
+		{"1__000", "line 1, col 1: malformed number 1__000: '_' must separate digits"},
+		{"100_", "line 1, col 1: malformed number 100_: '_' must separate digits"},
+		{"1_.5", "line 1, col 1: malformed number 1_.5: '_' must separate digits"},
+		{"0x_FF", "line 1, col 1: malformed number 0x_FF: '_' must separate digits"},
+		{"5days", "line 1, col 1: malformed number 5days: unexpected character 'd'"},
+		{"1e400", "line 1, col 1: number 1e400 is out of range"},
+		{"1.2.3", "line 1, col 1: malformed number literal 1.2.3: a number has a single decimal point"},
+		{"let x = 2 * 1.5.0", "line 1, col 13: malformed number literal 1.5.0: a number has a single decimal point"},
+		{"1.", "line 1, col 1: malformed number literal 1.: '.' must be followed by a digit"},
+		{"let y = 3.foo", "line 1, col 9: malformed number literal 3.: '.' must be followed by a digit"},
+		{"0xFFFF_FFFF_FFFF_FFFF", "line 1, col 1: number 0xFFFF_FFFF_FFFF_FFFF is out of range: hexadecimal literals must fit in a 64-bit signed integer"},
+		{"0b1" + strings.Repeat("0", 64), "line 1, col 1: number 0b1" + strings.Repeat("0", 64) + " is out of range: binary literals must fit in a 64-bit signed integer"},
+	}
+
+	for _, tt := range tests {
+		_, _, errs := parseAndEval(tt.source, nil)
+		if len(errs) == 0 || errs[0] != tt.expected {
+			t.Errorf("'%s': expected error %q, got %v", tt.source, tt.expected, errs)
+		}
+	}
+}
+
+func TestFailedModuleIsNotCached(t *testing.T) {
+	fail := true
+	registry := natives.NewRegistry()
+	registry.Register("load", func(args ...interface{}) (interface{}, error) {
+		if fail {
+			return nil, errors.New("unavailable")
+		}
+		return int64(1), nil
+	})
+	interp := New()
+	interp.SetNatives(registry)
+	interp.SetModuleLoader(func(name string) (*ast.Program, error) {
+		return parser.New(lexer.New("export const value = load()")).ParseProgram(), nil
+	})
+
+	if _, err := interp.loadModule("config"); err == nil {
+		t.Fatal("expected the module to fail")
+	}
+	if _, ok := interp.modules["config"]; ok {
+		t.Fatal("expected the failed module to be dropped from the cache")
+	}
+
+	fail = false
+	mod, err := interp.loadModule("config")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mod.exports["value"] != int64(1) {
+		t.Errorf("expected the retried module to export 1, got %v", mod.exports["value"])
+	}
+}
@@ -0,0 +1,161 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/issadicko/kodi-script-go/ast"
+)
+
+// ModuleLoader returns the parsed program of the module with the given name.
+// It is called at most once per module and per Eval.
+type ModuleLoader func(name string) (*ast.Program, error)
+
+// module is a module evaluated during the current Eval.
+type module struct {
+	exports map[string]Value
+	loading bool // still being evaluated, so importing it again is a cycle
+}
+
+// SetModuleLoader enables import statements, using loader to obtain the
+// program of each imported module.
+func (i *Interpreter) SetModuleLoader(loader ModuleLoader) {
+	i.moduleLoader = loader
+}
+
+// evalImportStatement binds the requested exports of a module as constants
+// in the current environment.
+func (i *Interpreter) evalImportStatement(stmt *ast.ImportStatement) error {
+	mod, err := i.loadModule(stmt.Source)
+	if err != nil {
+		return wrapError(err, stmt.Token, ErrorKindRuntime)
+	}
+
+	for _, spec := range stmt.Specifiers {
+		val, ok := mod.exports[spec.Name.Value]
+		if !ok {
+			err := newRuntimeError(ErrorKindReference, fmt.Sprintf("module %q does not export '%s'", stmt.Source, spec.Name.Value))
+			return wrapError(err, spec.Name.Token, ErrorKindReference)
+		}
+		local := spec.LocalName()
+		if err := i.declare(local.Value, val, true); err != nil {
+			return wrapError(err, local.Token, ErrorKindType)
+		}
+	}
+	return nil
+}
+
+// loadModule evaluates a module the first time it is imported and returns
+// its exports. Modules run in their own global scope: they can use natives
+// and host functions but not the variables of the importing script.
+func (i *Interpreter) loadModule(name string) (*module, error) {
+	if mod, ok := i.modules[name]; ok {
+		if mod.loading {
+			return nil, fmt.Errorf("import cycle: %s", i.importCycle(name))
+		}
+		return mod, nil
+	}
+
+	if i.moduleLoader == nil {
+		return nil, fmt.Errorf("cannot import %q: no module resolver configured", name)
+	}
+	program, err := i.moduleLoader(name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load module %q: %w", name, err)
+	}
+
+	mod := &module{exports: make(map[string]Value), loading: true}
+	if i.modules == nil {
+		i.modules = make(map[string]*module)
+	}
+	i.modules[name] = mod
+	i.importStack = append(i.importStack, name)
+
+	savedEnv := i.env
+	i.env = NewEnclosedEnvironment(nil)
+	err = i.evalModule(program, mod)
+	i.env = savedEnv
+
+	i.importStack = i.importStack[:len(i.importStack)-1]
+	mod.loading = false
+	if err != nil {
+		// A later import retries the module instead of seeing its partial exports
+		delete(i.modules, name)
+		return nil, err
+	}
+	return mod, nil
+}
+
+// evalModule runs a module's top-level statements in the current
+// environment, then records the values of its exported declarations.
+func (i *Interpreter) evalModule(program *ast.Program, mod *module) error {
+	if err := i.hoistFunctions(program.Statements); err != nil {
+		return err
+	}
+
+	for _, stmt := range program.Statements {
+		val, err := i.evalStatement(stmt)
+		if err != nil {
+			return err
+		}
+		if _, ok := val.(*ReturnValue); ok {
+			break
+		}
+		if err := loopSignalError(val); err != nil {
+			return err
+		}
+	}
+
+	for _, stmt := range program.Statements {
+		export, ok := stmt.(*ast.ExportStatement)
+		if !ok {
+			continue
+		}
+		for _, name := range declaredNames(export.Declaration) {
+			mod.exports[name], _ = i.env.Get(name)
+		}
+	}
+	return nil
+}
+
+// importCycle describes the chain of imports that leads back to name.
+func (i *Interpreter) importCycle(name string) string {
+	for idx, entry := range i.importStack {
+		if entry == name {
+			cycle := append([]string{}, i.importStack[idx:]...)
+			return strings.Join(append(cycle, name), " -> ")
+		}
+	}
+	return name
+}
+
+// declaredNames returns the names bound by a let, const or fn declaration.
+func declaredNames(stmt ast.Statement) []string {
+	switch s := stmt.(type) {
+	case *ast.FunctionDecl:
+		return []string{s.Name.Value}
+	case *ast.VarDecl:
+		if s.Pattern == nil {
+			return []string{s.Name.Value}
+		}
+		return patternNames(s.Pattern, nil)
+	}
+	return nil
+}
+
+// patternNames appends the names bound by a destructuring pattern to names.
+func patternNames(target ast.Expression, names []string) []string {
+	switch t := target.(type) {
+	case *ast.Identifier:
+		names = append(names, t.Value)
+	case *ast.ObjectPattern:
+		for _, prop := range t.Properties {
+			names = patternNames(prop.Target, names)
+		}
+	case *ast.ArrayPattern:
+		for _, elem := range t.Elements {
+			names = patternNames(elem.Target, names)
+		}
+	}
+	return names
+}
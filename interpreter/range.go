@@ -0,0 +1,191 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/issadicko/kodi-script-go/convert"
+)
+
+// Range is the lazy value of a range expression: a..b includes b, a..<b
+// stops before it. Iterating a range yields its start, start+1, ... without
+// materializing an array; a range whose end is before its start is empty.
+// When both bounds are integers the range is an integer range: its bounds
+// are kept exactly in IntStart and IntEnd and it yields integers. Otherwise
+// the bounds are Start and End.
+type Range struct {
+	Start     float64
+	End       float64
+	IntStart  int64
+	IntEnd    int64
+	Inclusive bool
+	Integer   bool
+}
+
+// Len returns the number of values the range yields, capped at math.MaxInt.
+func (r Range) Len() int {
+	if r.Integer {
+		end := r.IntEnd
+		if !r.Inclusive {
+			if end == math.MinInt64 {
+				return 0
+			}
+			end--
+		}
+		if end < r.IntStart {
+			return 0
+		}
+		// The distance always fits in a uint64, even across zero
+		distance := uint64(end) - uint64(r.IntStart)
+		if distance >= math.MaxInt {
+			return math.MaxInt
+		}
+		return int(distance) + 1
+	}
+
+	var n float64
+	if r.Inclusive {
+		if r.End < r.Start {
+			return 0
+		}
+		n = math.Floor(r.End-r.Start) + 1
+	} else {
+		if r.End <= r.Start {
+			return 0
+		}
+		n = math.Ceil(r.End - r.Start)
+	}
+	if n >= math.MaxInt {
+		return math.MaxInt
+	}
+	return int(n)
+}
+
+// At returns the value at position idx.
+func (r Range) At(idx int) interface{} {
+	if r.Integer {
+		return r.IntStart + int64(idx)
+	}
+	return r.Start + float64(idx)
+}
+
+// Contains reports whether v is a number between the bounds of the range.
+// An integer range only contains the integers it yields, so 1.5 is not in
+// 1..3 while 2.0 is.
+func (r Range) Contains(v Value) bool {
+	if r.Integer {
+		if n, ok := convert.Integer(v); ok {
+			if r.Inclusive {
+				return n >= r.IntStart && n <= r.IntEnd
+			}
+			return n >= r.IntStart && n < r.IntEnd
+		}
+	}
+	n, ok := toNumber(v)
+	if !ok || r.Integer && n != math.Trunc(n) {
+		return false
+	}
+	start, end := r.Start, r.End
+	if r.Integer {
+		start, end = float64(r.IntStart), float64(r.IntEnd)
+	}
+	if r.Inclusive {
+		return n >= start && n <= end
+	}
+	return n >= start && n < end
+}
+
+func (r Range) String() string {
+	op := "..<"
+	if r.Inclusive {
+		op = ".."
+	}
+	if r.Integer {
+		return toString(r.IntStart) + op + toString(r.IntEnd)
+	}
+	return toString(r.Start) + op + toString(r.End)
+}
+
+// newRange builds a Range from evaluated bounds.
+func newRange(start, end Value, inclusive bool) (Range, error) {
+	si, sint := convert.Integer(start)
+	ei, eint := convert.Integer(end)
+	if sint && eint {
+		return Range{IntStart: si, IntEnd: ei, Inclusive: inclusive, Integer: true}, nil
+	}
+	s, sok := toNumber(start)
+	e, eok := toNumber(end)
+	if !sok || !eok {
+		return Range{}, fmt.Errorf("range bounds must be numbers, got %T and %T", start, end)
+	}
+	return Range{Start: s, End: e, Inclusive: inclusive}, nil
+}
+
+// forIterator walks the entries of a for-in iterable. Each entry has a key
+// (the index, or the property name for objects) and a value.
+type forIterator struct {
+	size  int
+	entry func(idx int) (key, value Value)
+	keyed bool // a single loop variable receives the key rather than the value
+}
+
+// newForIterator returns an iterator over an array, a range or an object,
+// or over a Go slice, array or map. Object keys are visited in sorted order.
+func newForIterator(iterable Value) (*forIterator, error) {
+	switch v := iterable.(type) {
+	case []interface{}:
+		return &forIterator{
+			size:  len(v),
+			entry: func(idx int) (Value, Value) { return int64(idx), v[idx] },
+		}, nil
+
+	case Range:
+		return &forIterator{
+			size:  v.Len(),
+			entry: func(idx int) (Value, Value) { return int64(idx), v.At(idx) },
+		}, nil
+
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return &forIterator{
+			size:  len(keys),
+			entry: func(idx int) (Value, Value) { return keys[idx], v[keys[idx]] },
+			keyed: true,
+		}, nil
+
+	default:
+		if rv, ok := convert.Collection(iterable); ok {
+			return newReflectIterator(rv), nil
+		}
+		return nil, fmt.Errorf("for-in requires an array, a range or an object, got %T", iterable)
+	}
+}
+
+// newReflectIterator iterates a Go slice, array or map bound by the host,
+// converting each element as it is visited. Maps behave like objects.
+func newReflectIterator(collection reflect.Value) *forIterator {
+	if collection.Kind() != reflect.Map {
+		return &forIterator{
+			size: collection.Len(),
+			entry: func(idx int) (Value, Value) {
+				return int64(idx), convert.FromGo(collection.Index(idx))
+			},
+		}
+	}
+
+	keys := collection.MapKeys()
+	sort.Slice(keys, func(a, b int) bool { return keys[a].String() < keys[b].String() })
+	return &forIterator{
+		size: len(keys),
+		entry: func(idx int) (Value, Value) {
+			return keys[idx].String(), convert.FromGo(collection.MapIndex(keys[idx]))
+		},
+		keyed: true,
+	}
+}
@@ -3,9 +3,18 @@ package interpreter
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 	"reflect"
+	"strings"
+
+	"github.com/issadicko/kodi-script-go/convert"
+	"github.com/issadicko/kodi-script-go/decimal"
 )
 
+// decimalType is the Go type of KodiScript decimals.
+var decimalType = reflect.TypeOf((*big.Rat)(nil))
+
 // reflectivePropertyAccess uses reflection to access properties on Go objects.
 func (i *Interpreter) reflectivePropertyAccess(object Value, propertyName string) (Value, error) {
 	val := reflect.ValueOf(object)
@@ -29,17 +38,94 @@ func (i *Interpreter) reflectivePropertyAccess(object Value, propertyName string
 		}, nil
 	}
 
+	// Typed maps behave like objects: a missing key is null
+	if val.Kind() == reflect.Map && val.Type().Key().Kind() == reflect.String {
+		elem, _ := mapIndex(val, propertyName)
+		return elem, nil
+	}
+
 	// Try to access field
 	if val.Kind() == reflect.Struct {
 		field := val.FieldByName(propertyName)
 		if field.IsValid() && field.CanInterface() {
-			return field.Interface(), nil
+			return convert.FromGo(field), nil
 		}
 	}
 
 	return nil, fmt.Errorf("property or method '%s' not found on %T", propertyName, object)
 }
 
+// lookupField returns the value of a key on an object or Go map, or of an
+// exported field on a Go struct, and whether it exists.
+func lookupField(object Value, name string) (Value, bool) {
+	if m, ok := object.(map[string]interface{}); ok {
+		val, ok := m[name]
+		return val, ok
+	}
+
+	val := reflect.ValueOf(object)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil, false
+		}
+		val = val.Elem()
+	}
+	if val.Kind() == reflect.Map && val.Type().Key().Kind() == reflect.String {
+		return mapIndex(val, name)
+	}
+	if val.Kind() != reflect.Struct {
+		return nil, false
+	}
+	field := val.FieldByName(name)
+	if !field.IsValid() || !field.CanInterface() {
+		return nil, false
+	}
+	return convert.FromGo(field), true
+}
+
+// reflectiveFieldAssign sets an exported field on a Go struct, converting the value
+// with convertToGoType. Structs held by value are copied, updated and returned
+// with replaced set so the caller can store the copy back.
+func reflectiveFieldAssign(object Value, fieldName string, value Value) (Value, bool, error) {
+	val := reflect.ValueOf(object)
+
+	target := val
+	for target.Kind() == reflect.Ptr {
+		if target.IsNil() {
+			return nil, false, fmt.Errorf("cannot set field '%s' on nil pointer", fieldName)
+		}
+		target = target.Elem()
+	}
+	if target.Kind() != reflect.Struct {
+		return nil, false, fmt.Errorf("cannot set property '%s' on %T", fieldName, object)
+	}
+
+	replaced := false
+	if !target.CanSet() {
+		// Struct held by value: update an addressable copy
+		copied := reflect.New(target.Type()).Elem()
+		copied.Set(target)
+		target = copied
+		replaced = true
+	}
+
+	field := target.FieldByName(fieldName)
+	if !field.IsValid() || !field.CanSet() {
+		return nil, false, fmt.Errorf("field '%s' not found or not settable on %T", fieldName, object)
+	}
+
+	converted, err := convertToGoType(value, field.Type())
+	if err != nil {
+		return nil, false, fmt.Errorf("field '%s': %w", fieldName, err)
+	}
+	field.Set(converted)
+
+	if replaced {
+		return target.Interface(), true, nil
+	}
+	return object, false, nil
+}
+
 // findMethod tries to find a method on a value or its pointer type.
 func findMethod(val reflect.Value, name string) reflect.Value {
 	// Try on the value itself
@@ -88,22 +174,22 @@ func callReflectedMethod(method reflect.Value, args []interface{}) (interface{},
 	case 0:
 		return nil, nil
 	case 1:
-		return convertFromGoType(out[0]), nil
+		return convert.FromGo(out[0]), nil
 	case 2:
 		// Check if second return is error
 		if out[1].Type().Implements(reflect.TypeOf((*error)(nil)).Elem()) {
 			if !out[1].IsNil() {
 				return nil, out[1].Interface().(error)
 			}
-			return convertFromGoType(out[0]), nil
+			return convert.FromGo(out[0]), nil
 		}
 		// Return both values as array
-		return []interface{}{convertFromGoType(out[0]), convertFromGoType(out[1])}, nil
+		return []interface{}{convert.FromGo(out[0]), convert.FromGo(out[1])}, nil
 	default:
 		// Multiple return values - return as array
 		results := make([]interface{}, len(out))
 		for i, v := range out {
-			results[i] = convertFromGoType(v)
+			results[i] = convert.FromGo(v)
 		}
 		return results, nil
 	}
@@ -123,30 +209,49 @@ func convertToGoType(val interface{}, targetType reflect.Type) (reflect.Value, e
 		return reflect.ValueOf(val), nil
 	}
 
+	// Decimals convert from numbers and numeric strings
+	if targetType == decimalType {
+		if d, ok := decimal.From(val); ok {
+			return reflect.ValueOf(d), nil
+		}
+		if s, ok := val.(string); ok {
+			if d, ok := new(big.Rat).SetString(strings.TrimSpace(s)); ok {
+				return reflect.ValueOf(d), nil
+			}
+		}
+	}
+
 	// Handle numeric conversions
 	switch targetType.Kind() {
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		if f, ok := val.(float64); ok {
-			return reflect.ValueOf(int(f)).Convert(targetType), nil
+			if math.IsInf(f, 0) || math.IsNaN(f) {
+				return reflect.Value{}, fmt.Errorf("%v is out of range for %s", f, targetType)
+			}
+			// Floats are truncated toward zero, like int()
+			val, _ = big.NewFloat(math.Trunc(f)).Int(nil)
 		}
-		if i, ok := val.(int); ok {
-			return reflect.ValueOf(i).Convert(targetType), nil
+		n, ok := val.(*big.Int)
+		if !ok {
+			n, ok = convert.BigInteger(val)
 		}
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		if f, ok := val.(float64); ok {
-			return reflect.ValueOf(uint(f)).Convert(targetType), nil
+		if ok {
+			return integerToGoType(n, targetType)
 		}
 	case reflect.Float32, reflect.Float64:
-		if f, ok := val.(float64); ok {
+		if f, ok := toNumber(val); ok {
 			return reflect.ValueOf(f).Convert(targetType), nil
 		}
-		if i, ok := val.(int); ok {
-			return reflect.ValueOf(float64(i)).Convert(targetType), nil
-		}
 	case reflect.String:
 		if s, ok := val.(string); ok {
 			return reflect.ValueOf(s), nil
 		}
+		if d, ok := val.(*big.Rat); ok {
+			return reflect.ValueOf(decimal.Format(d)).Convert(targetType), nil
+		}
+		// Go would read an integer as a rune code point, so refuse the conversion
+		return reflect.Value{}, fmt.Errorf("cannot convert %T to %s", val, targetType)
 	case reflect.Bool:
 		if b, ok := val.(bool); ok {
 			return reflect.ValueOf(b), nil
@@ -162,48 +267,82 @@ func convertToGoType(val interface{}, targetType reflect.Type) (reflect.Value, e
 	return reflect.Value{}, fmt.Errorf("cannot convert %T to %s", val, targetType)
 }
 
-// convertFromGoType converts a Go reflect.Value back to a KodiScript-compatible value.
-func convertFromGoType(val reflect.Value) interface{} {
-	if !val.IsValid() {
-		return nil
-	}
-
-	// Handle nil pointers
-	if val.Kind() == reflect.Ptr && val.IsNil() {
-		return nil
-	}
-
-	if !val.CanInterface() {
-		return nil
-	}
-
-	result := val.Interface()
-
-	// Convert Go ints to float64 (KodiScript's number type)
-	switch v := result.(type) {
-	case int:
-		return float64(v)
-	case int8:
-		return float64(v)
-	case int16:
-		return float64(v)
-	case int32:
-		return float64(v)
-	case int64:
-		return float64(v)
-	case uint:
-		return float64(v)
-	case uint8:
-		return float64(v)
-	case uint16:
-		return float64(v)
-	case uint32:
-		return float64(v)
-	case uint64:
-		return float64(v)
-	case float32:
-		return float64(v)
-	}
-
-	return result
+// integerToGoType converts n to an integer Go type, rejecting values the
+// type cannot hold instead of letting them wrap around.
+func integerToGoType(n *big.Int, targetType reflect.Type) (reflect.Value, error) {
+	target := reflect.New(targetType).Elem()
+	switch targetType.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n.Sign() >= 0 && n.IsUint64() && !target.OverflowUint(n.Uint64()) {
+			target.SetUint(n.Uint64())
+			return target, nil
+		}
+	default:
+		if n.IsInt64() && !target.OverflowInt(n.Int64()) {
+			target.SetInt(n.Int64())
+			return target, nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("%s is out of range for %s", n, targetType)
+}
+
+// reflectiveIndexAssign sets an element of a Go slice or array, or a key of
+// a string-keyed Go map, bound by the host. The value is converted to the
+// element type. Like reflectiveFieldAssign, it returns a replacement
+// collection when the original cannot be updated in place: an array held
+// by value is copied, and assigning at the length of a slice appends.
+func reflectiveIndexAssign(object Value, collection reflect.Value, key, value Value) (Value, bool, error) {
+	converted, err := convertToGoType(value, collection.Type().Elem())
+	if err != nil {
+		return nil, false, fmt.Errorf("element of %s: %w", collection.Type(), err)
+	}
+
+	if collection.Kind() == reflect.Map {
+		name, ok := key.(string)
+		if !ok {
+			return nil, false, fmt.Errorf("object key must be a string, got %T", key)
+		}
+		if collection.IsNil() {
+			return nil, false, fmt.Errorf("cannot set key '%s' on a nil %s", name, collection.Type())
+		}
+		collection.SetMapIndex(reflect.ValueOf(name).Convert(collection.Type().Key()), converted)
+		return object, false, nil
+	}
+
+	idx, ok := integralOperand(key)
+	if !ok {
+		return nil, false, fmt.Errorf("array index must be an integer")
+	}
+	length := int64(collection.Len())
+	switch {
+	case idx >= 0 && idx < length:
+		if collection.Kind() == reflect.Array && !collection.CanSet() {
+			// Array held by value: update an addressable copy
+			copied := reflect.New(collection.Type()).Elem()
+			copied.Set(collection)
+			copied.Index(int(idx)).Set(converted)
+			return copied.Interface(), true, nil
+		}
+		collection.Index(int(idx)).Set(converted)
+		return object, false, nil
+	case idx == length && collection.Kind() == reflect.Slice:
+		// Never grow into spare capacity another alias may share
+		grown := reflect.Append(collection.Slice3(0, int(length), int(length)), converted)
+		if collection.CanSet() {
+			collection.Set(grown)
+			return object, false, nil
+		}
+		return grown.Interface(), true, nil
+	default:
+		return nil, false, fmt.Errorf("array index out of range: %d (length %d)", idx, length)
+	}
+}
+
+// mapIndex looks up key in a reflected Go map with string keys.
+func mapIndex(m reflect.Value, key string) (Value, bool) {
+	elem := m.MapIndex(reflect.ValueOf(key).Convert(m.Type().Key()))
+	if !elem.IsValid() {
+		return nil, false
+	}
+	return convert.FromGo(elem), true
 }
@@ -23,6 +23,10 @@ type Script struct {
 	useCache    bool
 	maxOps      int64         // Maximum operations (0 = unlimited)
 	timeout     time.Duration // Execution timeout (0 = no timeout)
+	strictArity bool          // Reject calls with missing or extra arguments
+	legacyScope bool          // Pre block-scoping behavior for let and loop variables
+	collation   natives.Collation
+	resolver    ModuleResolver
 }
 
 // Result represents the result of script execution.
@@ -49,7 +53,28 @@ func (s *Script) WithCache(enabled bool) *Script {
 
 // WithVariables injects host variables into the script context.
 func (s *Script) WithVariables(vars map[string]interface{}) *Script {
-	s.interp = interpreter.NewWithEnv(vars)
+	if s.interp == nil {
+		s.interp = interpreter.NewWithEnv(vars)
+		return s
+	}
+	for name, value := range vars {
+		s.interp.SetGlobal(name, value)
+	}
+	return s
+}
+
+// WithConstants injects read-only host values into the script context.
+// Scripts can read them but any attempt to reassign or redeclare them
+// fails with a TypeError. Only the binding is frozen, as with a script
+// const: a property or index assignment such as config.key = v changes the
+// host's map or slice in place. Pass a copy to keep the original intact.
+func (s *Script) WithConstants(consts map[string]interface{}) *Script {
+	if s.interp == nil {
+		s.interp = interpreter.New()
+	}
+	for name, value := range consts {
+		s.interp.SetConstant(name, value)
+	}
 	return s
 }
 
@@ -90,34 +115,39 @@ func (s *Script) WithTimeout(timeout time.Duration) *Script {
 	return s
 }
 
-// Execute runs the script and returns the result.
-func (s *Script) Execute() *Result {
-	result := &Result{}
-
-	var program *ast.Program
+// WithStrictArity enables strict arity checking: calling a script function
+// with missing or extra arguments fails with an error at the call site
+// instead of binding null or ignoring the extra values.
+func (s *Script) WithStrictArity(strict bool) *Script {
+	s.strictArity = strict
+	return s
+}
 
-	// Try to get from cache first
-	if s.useCache {
-		if cached, ok := cache.DefaultCache.Get(s.source); ok {
-			program = cached
-		}
-	}
+// WithLegacyScoping restores the scoping rules of earlier versions, where
+// let declarations and loop variables inside if/for/while bodies remain
+// visible after the block. Use it for scripts written against that behavior.
+func (s *Script) WithLegacyScoping(legacy bool) *Script {
+	s.legacyScope = legacy
+	return s
+}
 
-	// Parse if not cached
-	if program == nil {
-		l := lexer.New(s.source)
-		p := parser.New(l)
-		program = p.ParseProgram()
+// WithCollation sets how strings are ordered by the comparison operators
+// and by sort, sortBy, min and max, for example with a locale-aware
+// collator. By default strings are compared byte by byte.
+func (s *Script) WithCollation(collate natives.Collation) *Script {
+	s.collation = collate
+	s.natives.SetCollation(collate)
+	return s
+}
 
-		if len(p.Errors()) > 0 {
-			result.Errors = p.Errors()
-			return result
-		}
+// Execute runs the script and returns the result.
+func (s *Script) Execute() *Result {
+	result := &Result{}
 
-		// Store in cache
-		if s.useCache {
-			cache.DefaultCache.Set(s.source, program)
-		}
+	program, errs := s.parse(s.source)
+	if len(errs) > 0 {
+		result.Errors = errs
+		return result
 	}
 
 	// Interpreter
@@ -133,6 +163,13 @@ func (s *Script) Execute() *Result {
 		s.interp.SetMaxOperations(s.maxOps)
 	}
 
+	s.interp.SetStrictArity(s.strictArity)
+	s.interp.SetLegacyScoping(s.legacyScope)
+	s.interp.SetCollation(s.collation)
+	if s.resolver != nil {
+		s.interp.SetModuleLoader(s.loadModule)
+	}
+
 	// Apply timeout if set
 	if s.timeout > 0 {
 		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
@@ -152,6 +189,30 @@ func (s *Script) Execute() *Result {
 	return result
 }
 
+// parse returns the program for source, using the AST cache when enabled.
+func (s *Script) parse(source string) (*ast.Program, []string) {
+	// Try to get from cache first
+	if s.useCache {
+		if cached, ok := cache.DefaultCache.Get(source); ok {
+			return cached, nil
+		}
+	}
+
+	l := lexer.New(source)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) > 0 {
+		return nil, p.Errors()
+	}
+
+	// Store in cache
+	if s.useCache {
+		cache.DefaultCache.Set(source, program)
+	}
+	return program, nil
+}
+
 // Run is a convenience function to execute KodiScript code with optional variables.
 func Run(source string, variables map[string]interface{}) *Result {
 	script := New(source)
@@ -1,6 +1,7 @@
 package kodi
 
 import (
+	"fmt"
 	"testing"
 )
 
@@ -9,7 +10,7 @@ func TestBasicVariableDeclaration(t *testing.T) {
 	if len(result.Errors) > 0 {
 		t.Fatalf("unexpected errors: %v", result.Errors)
 	}
-	if result.Value != float64(42) {
+	if result.Value != int64(42) {
 		t.Errorf("expected 42, got %v", result.Value)
 	}
 }
@@ -102,13 +103,15 @@ print("World")`, nil)
 func TestArithmeticOperations(t *testing.T) {
 	tests := []struct {
 		source   string
-		expected float64
+		expected interface{}
 	}{
-		{"5 + 3", 8},
-		{"10 - 4", 6},
-		{"3 * 4", 12},
-		{"20 / 5", 4},
-		{"(2 + 3) * 4", 20},
+		{"5 + 3", int64(8)},
+		{"10 - 4", int64(6)},
+		{"3 * 4", int64(12)},
+		{"20 / 5", int64(4)},
+		{"(2 + 3) * 4", int64(20)},
+		{"10 / 4", 2.5},
+		{"1.5 + 1", 2.5},
 	}
 
 	for _, tt := range tests {
@@ -175,7 +178,7 @@ total`, nil)
 	if len(result.Errors) > 0 {
 		t.Fatalf("unexpected errors: %v", result.Errors)
 	}
-	if result.Value != float64(60) {
+	if result.Value != int64(60) {
 		t.Errorf("expected 60, got %v", result.Value)
 	}
 }
@@ -207,12 +210,12 @@ func TestReturnStatement(t *testing.T) {
 		{
 			name:     "basic return",
 			source:   `return 42`,
-			expected: float64(42),
+			expected: int64(42),
 		},
 		{
 			name:     "return expression",
 			source:   `return 10 + 20`,
-			expected: float64(30),
+			expected: int64(30),
 		},
 		{
 			name:     "return string",
@@ -235,7 +238,7 @@ func TestReturnStatement(t *testing.T) {
 return x * 2
 let y = 100
 y`,
-			expected: float64(20),
+			expected: int64(20),
 		},
 		{
 			name: "return in if block",
@@ -282,7 +285,7 @@ print("after return")
 	if len(result.Errors) > 0 {
 		t.Fatalf("unexpected errors: %v", result.Errors)
 	}
-	if result.Value != float64(1) {
+	if result.Value != int64(1) {
 		t.Errorf("expected 1, got %v", result.Value)
 	}
 	if len(result.Output) != 1 {
@@ -296,18 +299,20 @@ print("after return")
 func TestMathFunctions(t *testing.T) {
 	tests := []struct {
 		source   string
-		expected float64
+		expected interface{}
 	}{
-		{`abs(-5)`, 5},
-		{`abs(5)`, 5},
-		{`floor(3.7)`, 3},
-		{`ceil(3.2)`, 4},
-		{`round(3.5)`, 4},
-		{`round(3.4)`, 3},
-		{`min(5, 3, 8, 1)`, 1},
-		{`max(5, 3, 8, 1)`, 8},
-		{`pow(2, 3)`, 8},
-		{`sqrt(16)`, 4},
+		{`abs(-5)`, int64(5)},
+		{`abs(5)`, int64(5)},
+		{`abs(-2.5)`, 2.5},
+		{`floor(3.7)`, 3.0},
+		{`ceil(3.2)`, 4.0},
+		{`round(3.5)`, 4.0},
+		{`round(3.4)`, 3.0},
+		{`min(5, 3, 8, 1)`, int64(1)},
+		{`max(5, 3, 8, 1)`, int64(8)},
+		{`max(5, 8.5)`, 8.5},
+		{`pow(2, 3)`, 8.0},
+		{`sqrt(16)`, 4.0},
 	}
 
 	for _, tt := range tests {
@@ -334,8 +339,12 @@ func TestStringFunctions(t *testing.T) {
 		{`contains("hello world", "foo")`, false},
 		{`startsWith("hello world", "hello")`, true},
 		{`endsWith("hello world", "world")`, true},
-		{`indexOf("hello world", "world")`, float64(6)},
-		{`indexOf("hello world", "foo")`, float64(-1)},
+		{`indexOf("hello world", "world")`, int64(6)},
+		{`indexOf("hello world", "foo")`, int64(-1)},
+		{`let prénom = "Zoë"
+padLeft(prénom, 5, ".") + substring(prénom, 2) + length(prénom)`, "..Zoëë3"},
+		{`let élève = {âge: 12}
+élève.âge`, int64(12)},
 	}
 
 	for _, tt := range tests {
@@ -393,7 +402,7 @@ func TestRandomFunctions(t *testing.T) {
 	if len(result.Errors) > 0 {
 		t.Fatalf("randomInt errors: %v", result.Errors)
 	}
-	if v, ok := result.Value.(float64); !ok || v < 1 || v > 10 {
+	if v, ok := result.Value.(int64); !ok || v < 1 || v > 10 {
 		t.Errorf("randomInt: expected int in [1,10], got %v", result.Value)
 	}
 
@@ -510,7 +519,7 @@ func TestArrayUtilityFunctions(t *testing.T) {
 	if len(result.Errors) > 0 {
 		t.Fatalf("size errors: %v", result.Errors)
 	}
-	if result.Value != float64(5) {
+	if result.Value != int64(5) {
 		t.Errorf("size: expected 5, got %v", result.Value)
 	}
 
@@ -636,7 +645,7 @@ count
 	if len(result.Errors) > 0 {
 		t.Fatalf("for loop with if errors: %v", result.Errors)
 	}
-	if result.Value != float64(2) {
+	if result.Value != int64(2) {
 		t.Errorf("expected 2, got %v", result.Value)
 	}
 
@@ -671,7 +680,7 @@ big
 	if len(result.Errors) > 0 {
 		t.Fatalf("for loop if-else errors: %v", result.Errors)
 	}
-	if result.Value != float64(2) {
+	if result.Value != int64(2) {
 		t.Errorf("expected 2 (big count), got %v", result.Value)
 	}
 }
@@ -686,7 +695,7 @@ func TestArrayAndObjectLiterals(t *testing.T) {
 	if !ok {
 		t.Fatalf("Expected array, got %T", result.Value)
 	}
-	if len(arr) != 3 || arr[0] != float64(1) {
+	if len(arr) != 3 || arr[0] != int64(1) {
 		t.Errorf("Array literal failed: %v", arr)
 	}
 
@@ -695,7 +704,7 @@ func TestArrayAndObjectLiterals(t *testing.T) {
 	if len(result.Errors) > 0 {
 		t.Fatalf("Array index errors: %v", result.Errors)
 	}
-	if result.Value != float64(20) {
+	if result.Value != int64(20) {
 		t.Errorf("Array index failed: %v", result.Value)
 	}
 
@@ -713,7 +722,7 @@ func TestArrayAndObjectLiterals(t *testing.T) {
 	if len(result.Errors) > 0 {
 		t.Fatalf("Object literal identifier key errors: %v", result.Errors)
 	}
-	if result.Value != float64(10) {
+	if result.Value != int64(10) {
 		t.Errorf("Object literal identifier key failed: %v", result.Value)
 	}
 
@@ -736,7 +745,7 @@ func TestUserFunctions(t *testing.T) {
 	if len(result.Errors) > 0 {
 		t.Fatalf("Function call errors: %v", result.Errors)
 	}
-	if result.Value != float64(10) {
+	if result.Value != int64(10) {
 		t.Errorf("Function call failed: %v", result.Value)
 	}
 
@@ -748,7 +757,7 @@ func TestUserFunctions(t *testing.T) {
 	if len(result.Errors) > 0 {
 		t.Fatalf("Explicit return errors: %v", result.Errors)
 	}
-	if result.Value != float64(20) {
+	if result.Value != int64(20) {
 		t.Errorf("Explicit return failed: %v", result.Value)
 	}
 
@@ -763,7 +772,7 @@ func TestUserFunctions(t *testing.T) {
 	if len(result.Errors) > 0 {
 		t.Fatalf("Closure errors: %v", result.Errors)
 	}
-	if result.Value != float64(4) {
+	if result.Value != int64(4) {
 		t.Errorf("Closure failed expected 4, got: %v", result.Value)
 	}
 
@@ -776,7 +785,7 @@ func TestUserFunctions(t *testing.T) {
 	if len(result.Errors) > 0 {
 		t.Fatalf("Higher order function errors: %v", result.Errors)
 	}
-	if result.Value != float64(10) {
+	if result.Value != int64(10) {
 		t.Errorf("Higher order function failed: %v", result.Value)
 	}
 
@@ -791,7 +800,7 @@ func TestUserFunctions(t *testing.T) {
 	if len(result.Errors) > 0 {
 		t.Fatalf("Recursion errors: %v", result.Errors)
 	}
-	if result.Value != float64(120) {
+	if result.Value != int64(120) {
 		t.Errorf("Recursion failed: %v", result.Value)
 	}
 }
@@ -875,3 +884,187 @@ func TestStringTemplates(t *testing.T) {
 		t.Errorf("Escaped $ failed: expected 'Price is $100', got %v", result.Value)
 	}
 }
+
+func TestStringLiteralForms(t *testing.T) {
+	result := Run("let name = 'Ada'\nlet card = `\n    Hello ${name},\n      see you soon\n    `\ncard", nil)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if result.Value != "Hello Ada,\n  see you soon" {
+		t.Errorf("expected indented raw template, got %q", result.Value)
+	}
+
+	result = Run(`'caf\u00e9 \u{1F600}'`, nil)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if result.Value != "caf\u00e9 \U0001F600" {
+		t.Errorf("expected unicode escapes to be decoded, got %q", result.Value)
+	}
+
+	result = Run("let price = 5€", nil)
+	if len(result.Errors) == 0 || result.Errors[0] != "line 1, col 14: unexpected character \"€\"" {
+		t.Errorf("expected unexpected character error, got %v", result.Errors)
+	}
+
+	result = Run("let a = 1\nlet s = \"abc\nlet b = 2", nil)
+	if len(result.Errors) == 0 || result.Errors[0] != "line 2, col 9: unterminated string" {
+		t.Errorf("expected unterminated string error, got %v", result.Errors)
+	}
+}
+
+func TestTryCatchHostFunctionError(t *testing.T) {
+	script := New(`let status = "ok"
+try {
+    chargeCard(100)
+} catch (e) {
+    status = e.kind + ": " + e.message
+}
+status`).RegisterFunction("chargeCard", func(args ...interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("card declined")
+	})
+
+	result := script.Execute()
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if result.Value != "NativeError: card declined" {
+		t.Errorf("expected 'NativeError: card declined', got %v", result.Value)
+	}
+}
+
+func TestStrictArity(t *testing.T) {
+	source := `fn add(a, b) { return a + (b ?: 0) }
+add(1)`
+
+	result := New(source).Execute()
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors without strict arity: %v", result.Errors)
+	}
+
+	result = New(source).WithStrictArity(true).Execute()
+	if len(result.Errors) == 0 {
+		t.Fatal("expected arity error")
+	}
+	if result.Errors[0] != "function 'add' expects 2 arguments, got 1" {
+		t.Errorf("unexpected error: %s", result.Errors[0])
+	}
+
+	// Defaults, rest parameters and callbacks stay valid in strict mode
+	result = New(`fn greet(name, greeting = "Bonjour", ...extra) { return greeting + " " + name }
+let names = map(["a", "b"], fn(x) { return greet(x) })
+greet("Alice", "Salut", 1, 2) + names[1]`).WithStrictArity(true).Execute()
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if result.Value != "Salut AliceBonjour b" {
+		t.Errorf("expected 'Salut AliceBonjour b', got %v", result.Value)
+	}
+}
+
+func TestWithConstants(t *testing.T) {
+	consts := map[string]interface{}{
+		"config": map[string]interface{}{"vat": 0.2},
+	}
+
+	result := New(`let total = 100 * (1 + config.vat)
+total`).WithConstants(consts).Execute()
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if result.Value != float64(120) {
+		t.Errorf("expected 120, got %v", result.Value)
+	}
+
+	result = New(`config = {vat: 0}`).WithConstants(consts).Execute()
+	if len(result.Errors) == 0 {
+		t.Fatal("expected an error when reassigning a constant")
+	}
+	if result.Errors[0] != "cannot assign to constant 'config'" {
+		t.Errorf("unexpected error: %s", result.Errors[0])
+	}
+
+	// Constants survive WithVariables and can be caught like other TypeErrors
+	result = New(`let status = "ok"
+try {
+    config = null
+} catch (e) {
+    status = e.kind
+}
+status + rate`).WithConstants(consts).WithVariables(map[string]interface{}{"rate": 1.0}).Execute()
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if result.Value != "TypeError1.0" {
+		t.Errorf("expected 'TypeError1.0', got %v", result.Value)
+	}
+
+	// Only the binding is frozen: members are shared with the host map
+	result = New(`config.vat = 0.1
+config.vat`).WithConstants(consts).Execute()
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if result.Value != 0.1 {
+		t.Errorf("expected 0.1, got %v", result.Value)
+	}
+	if vat := consts["config"].(map[string]interface{})["vat"]; vat != 0.1 {
+		t.Errorf("expected the host map to see the update, got %v", vat)
+	}
+}
+
+func TestExecuteTwice(t *testing.T) {
+	script := New(`const TVA = 0.2
+let total = price * (1 + TVA)
+fn label() { return "TTC" }
+total + " " + label() + " " + config.currency`).
+		WithVariables(map[string]interface{}{"price": 100}).
+		WithConstants(map[string]interface{}{"config": map[string]interface{}{"currency": "EUR"}})
+
+	for run := 1; run <= 2; run++ {
+		result := script.Execute()
+		if len(result.Errors) > 0 {
+			t.Fatalf("run %d: unexpected errors: %v", run, result.Errors)
+		}
+		if result.Value != "120.0 TTC EUR" {
+			t.Errorf("run %d: expected '120.0 TTC EUR', got %v", run, result.Value)
+		}
+	}
+
+	// Host constants still cannot be redeclared at the top level
+	result := New(`let config = 1`).WithConstants(map[string]interface{}{"config": 0}).Execute()
+	if len(result.Errors) == 0 || result.Errors[0] != "cannot redeclare constant 'config'" {
+		t.Errorf("expected a redeclaration error, got %v", result.Errors)
+	}
+}
+
+func TestLegacyScopingOption(t *testing.T) {
+	source := `if (true) { let flag = "set" }
+flag`
+
+	result := New(source).Execute()
+	if len(result.Errors) == 0 {
+		t.Fatal("expected an error: let inside a block is not visible outside it")
+	}
+
+	result = New(source).WithLegacyScoping(true).Execute()
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if result.Value != "set" {
+		t.Errorf("expected 'set', got %v", result.Value)
+	}
+}
+
+func TestPrintInsideFunctionsAndBlocks(t *testing.T) {
+	result := New(`let log = fn(msg) { print("log: " + msg) }
+for (n in [1, 2]) {
+    if (n > 1) { log(n) }
+}`).SilentPrint(true).Execute()
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if len(result.Output) != 1 || result.Output[0] != "log: 2" {
+		t.Errorf("expected [log: 2], got %v", result.Output)
+	}
+}
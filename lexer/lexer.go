@@ -2,18 +2,35 @@
 package lexer
 
 import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
 	"github.com/issadicko/kodi-script-go/token"
 )
 
+// Errors reported as the literal of an ILLEGAL token.
+var (
+	errUnterminatedString   = errors.New("unterminated string")
+	errInvalidUnicodeEscape = errors.New("invalid unicode escape")
+	errUnterminatedComment  = errors.New("unterminated comment")
+	errDanglingPoint        = errors.New("'.' must be followed by a digit")
+	errSecondPoint          = errors.New("a number has a single decimal point")
+)
+
 // Lexer tokenizes KodiScript source code.
 type Lexer struct {
 	input        string
 	position     int         // current position in input (points to current char)
 	readPosition int         // current reading position in input (after current char)
-	ch           byte        // current char under examination
+	ch           rune        // current character under examination
 	line         int         // current line number
 	column       int         // current column number
 	prevToken    token.Token // previous token for ASI
+	doc          []string    // /// comment lines waiting for the next token
 }
 
 // New creates a new Lexer for the given input.
@@ -26,28 +43,54 @@ func New(input string) *Lexer {
 	return l
 }
 
+// Clone returns an independent copy of the lexer, used by the parser to look
+// ahead without consuming tokens.
+func (l *Lexer) Clone() *Lexer {
+	clone := *l
+	return &clone
+}
+
 // readChar advances the lexer by one character.
 func (l *Lexer) readChar() {
+	l.position = l.readPosition
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
+		l.readPosition++
+	} else if b := l.input[l.readPosition]; b < utf8.RuneSelf {
+		l.ch = rune(b)
+		l.readPosition++
 	} else {
-		l.ch = l.input[l.readPosition]
+		r, size := utf8.DecodeRuneInString(l.input[l.readPosition:])
+		l.ch = r
+		l.readPosition += size
 	}
-	l.position = l.readPosition
-	l.readPosition++
 	l.column++
 }
 
 // peekChar returns the next character without advancing.
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
 	}
-	return l.input[l.readPosition]
+	if b := l.input[l.readPosition]; b < utf8.RuneSelf {
+		return rune(b)
+	}
+	r, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return r
 }
 
-// NextToken returns the next token from the input.
+// NextToken returns the next token from the input. The /// doc comments
+// just before a token are joined into its Doc field.
 func (l *Lexer) NextToken() token.Token {
+	tok := l.nextToken()
+	if len(l.doc) > 0 && tok.Type != token.NEWLINE {
+		tok.Doc = strings.Join(l.doc, "\n")
+		l.doc = nil
+	}
+	return tok
+}
+
+func (l *Lexer) nextToken() token.Token {
 	var tok token.Token
 
 	l.skipWhitespace()
@@ -60,23 +103,79 @@ func (l *Lexer) NextToken() token.Token {
 		if l.peekChar() == '=' {
 			l.readChar()
 			tok = token.Token{Type: token.EQ, Literal: "==", Line: l.line, Column: l.column - 1}
+		} else if l.peekChar() == '>' {
+			l.readChar()
+			tok = token.Token{Type: token.FAT_ARROW, Literal: "=>", Line: l.line, Column: l.column - 1}
 		} else {
 			tok = l.newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
-		tok = l.newToken(token.PLUS, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.PLUS_ASSIGN, Literal: "+=", Line: l.line, Column: l.column - 1}
+		} else if l.peekChar() == '+' && l.isUpdate() {
+			l.readChar()
+			tok = token.Token{Type: token.INCREMENT, Literal: "++", Line: l.line, Column: l.column - 1}
+		} else {
+			tok = l.newToken(token.PLUS, l.ch)
+		}
 	case '-':
-		tok = l.newToken(token.MINUS, l.ch)
+		if l.peekChar() == '>' {
+			l.readChar()
+			tok = token.Token{Type: token.ARROW, Literal: "->", Line: l.line, Column: l.column - 1}
+		} else if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.MINUS_ASSIGN, Literal: "-=", Line: l.line, Column: l.column - 1}
+		} else if l.peekChar() == '-' && l.isUpdate() {
+			l.readChar()
+			tok = token.Token{Type: token.DECREMENT, Literal: "--", Line: l.line, Column: l.column - 1}
+		} else {
+			tok = l.newToken(token.MINUS, l.ch)
+		}
 	case '*':
-		tok = l.newToken(token.ASTERISK, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.ASTERISK_ASSIGN, Literal: "*=", Line: l.line, Column: l.column - 1}
+		} else if l.peekChar() == '*' {
+			l.readChar()
+			tok = token.Token{Type: token.POWER, Literal: "**", Line: l.line, Column: l.column - 1}
+		} else {
+			tok = l.newToken(token.ASTERISK, l.ch)
+		}
 	case '/':
 		if l.peekChar() == '/' {
-			l.skipLineComment()
-			return l.NextToken()
+			l.readLineComment()
+			return l.nextToken()
+		}
+		if l.peekChar() == '*' {
+			multiline, err := l.skipBlockComment()
+			if err != nil {
+				tok.Type = token.ILLEGAL
+				tok.Literal = err.Error()
+				l.prevToken = tok
+				return tok
+			}
+			// A comment spanning lines ends a statement like a newline would
+			if multiline && l.prevToken.Type.CanEndStatement() {
+				tok = token.Token{Type: token.NEWLINE, Literal: "\\n", Line: l.line, Column: l.column}
+				l.prevToken = tok
+				return tok
+			}
+			return l.nextToken()
+		}
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.SLASH_ASSIGN, Literal: "/=", Line: l.line, Column: l.column - 1}
+		} else {
+			tok = l.newToken(token.SLASH, l.ch)
 		}
-		tok = l.newToken(token.SLASH, l.ch)
 	case '%':
-		tok = l.newToken(token.PERCENT, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.PERCENT_ASSIGN, Literal: "%=", Line: l.line, Column: l.column - 1}
+		} else {
+			tok = l.newToken(token.PERCENT, l.ch)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			l.readChar()
@@ -88,6 +187,9 @@ func (l *Lexer) NextToken() token.Token {
 		if l.peekChar() == '=' {
 			l.readChar()
 			tok = token.Token{Type: token.LT_EQ, Literal: "<=", Line: l.line, Column: l.column - 1}
+		} else if l.peekChar() == '<' {
+			l.readChar()
+			tok = token.Token{Type: token.SHIFT_LEFT, Literal: "<<", Line: l.line, Column: l.column - 1}
 		} else {
 			tok = l.newToken(token.LT, l.ch)
 		}
@@ -95,6 +197,9 @@ func (l *Lexer) NextToken() token.Token {
 		if l.peekChar() == '=' {
 			l.readChar()
 			tok = token.Token{Type: token.GT_EQ, Literal: ">=", Line: l.line, Column: l.column - 1}
+		} else if l.peekChar() == '>' {
+			l.readChar()
+			tok = token.Token{Type: token.SHIFT_RIGHT, Literal: ">>", Line: l.line, Column: l.column - 1}
 		} else {
 			tok = l.newToken(token.GT, l.ch)
 		}
@@ -103,15 +208,19 @@ func (l *Lexer) NextToken() token.Token {
 			l.readChar()
 			tok = token.Token{Type: token.AND, Literal: "&&", Line: l.line, Column: l.column - 1}
 		} else {
-			tok = l.newToken(token.ILLEGAL, l.ch)
+			tok = l.newToken(token.BIT_AND, l.ch)
 		}
 	case '|':
 		if l.peekChar() == '|' {
 			l.readChar()
 			tok = token.Token{Type: token.OR, Literal: "||", Line: l.line, Column: l.column - 1}
 		} else {
-			tok = l.newToken(token.ILLEGAL, l.ch)
+			tok = l.newToken(token.BIT_OR, l.ch)
 		}
+	case '^':
+		tok = l.newToken(token.BIT_XOR, l.ch)
+	case '~':
+		tok = l.newToken(token.BIT_NOT, l.ch)
 	case '?':
 		if l.peekChar() == '.' {
 			l.readChar()
@@ -120,7 +229,7 @@ func (l *Lexer) NextToken() token.Token {
 			l.readChar()
 			tok = token.Token{Type: token.ELVIS, Literal: "?:", Line: l.line, Column: l.column - 1}
 		} else {
-			tok = l.newToken(token.ILLEGAL, l.ch)
+			tok = l.newToken(token.QUESTION, l.ch)
 		}
 	case ',':
 		tok = l.newToken(token.COMMA, l.ch)
@@ -141,12 +250,36 @@ func (l *Lexer) NextToken() token.Token {
 	case ']':
 		tok = l.newToken(token.RBRACKET, l.ch)
 	case '.':
-		tok = l.newToken(token.DOT, l.ch)
-	case '"':
-		str, isTemplate := l.readString()
-		if isTemplate {
-			tok.Type = token.STRING_TEMPLATE
+		if l.peekChar() == '.' {
+			l.readChar()
+			if l.peekChar() == '<' {
+				l.readChar()
+				tok = token.Token{Type: token.DOT_DOT_LT, Literal: "..<", Line: l.line, Column: l.column - 2}
+			} else if l.peekChar() == '.' {
+				l.readChar()
+				tok = token.Token{Type: token.ELLIPSIS, Literal: "...", Line: l.line, Column: l.column - 2}
+			} else {
+				tok = token.Token{Type: token.DOT_DOT, Literal: "..", Line: l.line, Column: l.column - 1}
+			}
 		} else {
+			tok = l.newToken(token.DOT, l.ch)
+		}
+	case '"', '\'', '`':
+		var str string
+		var isTemplate bool
+		var err error
+		if l.ch == '`' {
+			str, isTemplate, err = l.readRawString()
+		} else {
+			str, isTemplate, err = l.readString(l.ch)
+		}
+		switch {
+		case err != nil:
+			tok.Type = token.ILLEGAL
+			str = err.Error()
+		case isTemplate:
+			tok.Type = token.STRING_TEMPLATE
+		default:
 			tok.Type = token.STRING
 		}
 		tok.Literal = str
@@ -159,7 +292,7 @@ func (l *Lexer) NextToken() token.Token {
 			l.line++
 			l.column = 0
 			l.readChar()
-			return l.NextToken()
+			return l.nextToken()
 		}
 		l.line++
 		l.column = 0
@@ -173,8 +306,13 @@ func (l *Lexer) NextToken() token.Token {
 			l.prevToken = tok
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Literal = l.readNumber()
+			literal, err := l.readNumber()
+			tok.Literal = literal
 			tok.Type = token.NUMBER
+			if err != nil {
+				tok.Literal = "malformed number literal " + literal + ": " + err.Error()
+				tok.Type = token.ILLEGAL
+			}
 			l.prevToken = tok
 			return tok
 		} else {
@@ -188,7 +326,7 @@ func (l *Lexer) NextToken() token.Token {
 }
 
 // newToken creates a new token with the given type and character.
-func (l *Lexer) newToken(tokenType token.Type, ch byte) token.Token {
+func (l *Lexer) newToken(tokenType token.Type, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch), Line: l.line, Column: l.column}
 }
 
@@ -199,78 +337,316 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
-// skipLineComment skips a // comment until end of line.
-func (l *Lexer) skipLineComment() {
+// isUpdate reports whether the ++ or -- under the cursor is an update. Updates
+// are statements: a postfix one follows an identifier, ] or ) and ends the
+// statement, a prefix one starts a statement before its target. Anywhere
+// else the two characters are two signs, so 5--3 is 5 - (-3), a--b is
+// a - (-b) and a - --b is a - (-(-b)).
+func (l *Lexer) isUpdate() bool {
+	next := l.readPosition + 1
+	for next < len(l.input) && (l.input[next] == ' ' || l.input[next] == '\t') {
+		next++
+	}
+	var ch rune
+	if next < len(l.input) {
+		ch, _ = utf8.DecodeRuneInString(l.input[next:])
+	}
+
+	switch l.prevToken.Type {
+	case token.IDENT, token.RPAREN, token.RBRACKET:
+		return !startsOperand(ch)
+	}
+	switch l.prevToken.Type {
+	case token.ILLEGAL, token.NEWLINE, token.SEMICOLON, token.LBRACE, token.RBRACE:
+		return isLetter(ch)
+	}
+	return false
+}
+
+// startsOperand reports whether ch can begin an operand of a binary operator.
+func startsOperand(ch rune) bool {
+	return isLetter(ch) || isDigit(ch) || strings.ContainsRune("([{\"'`!~+-.", ch)
+}
+
+// readLineComment skips a // comment until end of line. The text of a ///
+// doc comment is kept for the next token; //// and longer runs of slashes
+// are plain comments.
+func (l *Lexer) readLineComment() {
+	position := l.position
 	for l.ch != '\n' && l.ch != 0 {
 		l.readChar()
 	}
+	text := l.input[position:l.position]
+	if strings.HasPrefix(text, "///") && !strings.HasPrefix(text, "////") {
+		text = strings.TrimPrefix(text[3:], " ")
+		l.doc = append(l.doc, strings.TrimRight(text, "\r"))
+	}
+}
+
+// skipBlockComment skips a /* */ comment, which may be nested. It reports
+// whether the comment spans several lines, and an error when it is not
+// closed; the error is located at the opening /*.
+func (l *Lexer) skipBlockComment() (bool, error) {
+	multiline := false
+	depth := 0
+	for {
+		switch {
+		case l.ch == 0:
+			return multiline, errUnterminatedComment
+		case l.ch == '/' && l.peekChar() == '*':
+			depth++
+			l.readChar()
+		case l.ch == '*' && l.peekChar() == '/':
+			depth--
+			l.readChar()
+			if depth == 0 {
+				l.readChar()
+				return multiline, nil
+			}
+		case l.ch == '\n':
+			multiline = true
+			l.trackNewline()
+		}
+		l.readChar()
+	}
 }
 
 // readIdentifier reads an identifier (letter followed by letters/digits).
+// Combining marks are kept, so a decomposed é (e + U+0301) is one letter.
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) || isDigit(l.ch) {
+	for isLetter(l.ch) || isDigit(l.ch) || unicode.IsMark(l.ch) {
 		l.readChar()
 	}
 	return l.input[position:l.position]
 }
 
-// readNumber reads a number (integer or float).
-func (l *Lexer) readNumber() string {
+// readNumber reads a number literal: an integer or float with an optional
+// exponent (1.5e-3), an integer with a 0x, 0o or 0b prefix, digits grouped
+// with underscores (1_000_000), and an optional d suffix marking a decimal
+// literal: 19.99d. Letters and digits stuck to the number are kept in the
+// literal, so that the parser reports a malformed number such as 0b102 or
+// 1e as a whole. A decimal point that is not followed by a digit, or a
+// second one that is, is an error: 1.foo or 1.2.3 are not numbers.
+func (l *Lexer) readNumber() (string, error) {
 	position := l.position
-	for isDigit(l.ch) {
+	if l.ch == '0' && strings.ContainsRune("xXoObB", l.peekChar()) {
 		l.readChar()
-	}
-	// Handle decimal numbers
-	if l.ch == '.' && isDigit(l.peekChar()) {
-		l.readChar() // consume '.'
-		for isDigit(l.ch) {
+		l.readChar()
+	} else {
+		for isDigit(l.ch) || l.ch == '_' {
+			l.readChar()
+		}
+		if l.ch == '.' && l.peekChar() != '.' {
+			l.readChar() // consume '.'
+			if !isDigit(l.ch) {
+				return l.input[position:l.position], errDanglingPoint
+			}
+			for isDigit(l.ch) || l.ch == '_' {
+				l.readChar()
+			}
+			if l.ch == '.' && isDigit(l.peekChar()) {
+				for isDigit(l.ch) || l.ch == '_' || l.ch == '.' {
+					l.readChar()
+				}
+				return l.input[position:l.position], errSecondPoint
+			}
+		}
+		if l.ch == 'e' || l.ch == 'E' {
+			if next := l.peekChar(); next == '+' || next == '-' {
+				l.readChar() // the sign belongs to the exponent
+			}
 			l.readChar()
 		}
 	}
-	return l.input[position:l.position]
+	for isLetter(l.ch) || isDigit(l.ch) {
+		l.readChar()
+	}
+	return l.input[position:l.position], nil
 }
 
-// readString reads a string literal (with escape support).
-// Returns the string content and whether it contains template expressions.
-func (l *Lexer) readString() (string, bool) {
-	var result []byte
+// readString reads a string literal delimited by quote (" or '), with
+// escape support. It returns the content, whether it contains template
+// expressions, and an error for an unterminated string or a malformed escape.
+func (l *Lexer) readString(quote rune) (string, bool, error) {
+	var result strings.Builder
+	var escapeErr error
 	isTemplate := false
 	l.readChar() // skip opening quote
-	for l.ch != '"' && l.ch != 0 {
+	for l.ch != quote {
+		if l.ch == 0 {
+			return "", false, errUnterminatedString
+		}
 		if l.ch == '\\' {
 			l.readChar()
-			switch l.ch {
-			case 'n':
-				result = append(result, '\n')
-			case 't':
-				result = append(result, '\t')
-			case '"':
-				result = append(result, '"')
-			case '\\':
-				result = append(result, '\\')
-			case '$':
-				result = append(result, '$')
-			default:
-				result = append(result, l.ch)
+			if err := l.readEscape(&result); err != nil && escapeErr == nil {
+				escapeErr = err
 			}
 		} else if l.ch == '$' && l.peekChar() == '{' {
 			// Template expression detected
 			isTemplate = true
-			result = append(result, '$', '{')
+			result.WriteString("${")
 			l.readChar() // consume $
 		} else {
-			result = append(result, l.ch)
+			result.WriteRune(l.ch)
+			l.trackNewline()
 		}
 		l.readChar()
 	}
-	return string(result), isTemplate
+	return result.String(), isTemplate, escapeErr
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+// readRawString reads a backtick string: no escape sequences, newlines kept
+// and ${...} templates allowed. A multiline string loses the indentation
+// common to its lines, see trimIndent.
+func (l *Lexer) readRawString() (string, bool, error) {
+	l.readChar() // skip opening backtick
+	position := l.position
+	isTemplate := false
+	for l.ch != '`' {
+		if l.ch == 0 {
+			return "", false, errUnterminatedString
+		}
+		if l.ch == '$' && l.peekChar() == '{' {
+			isTemplate = true
+		}
+		l.trackNewline()
+		l.readChar()
+	}
+	return trimIndent(l.input[position:l.position]), isTemplate, nil
+}
+
+// readEscape writes the character of the escape sequence whose backslash
+// was just consumed. Unknown escapes such as \" \' \\ or \$ stand for the
+// escaped character itself.
+func (l *Lexer) readEscape(result *strings.Builder) error {
+	switch l.ch {
+	case 0:
+		return errUnterminatedString
+	case 'n':
+		result.WriteByte('\n')
+	case 't':
+		result.WriteByte('\t')
+	case 'r':
+		result.WriteByte('\r')
+	case '0':
+		result.WriteByte(0)
+	case 'u':
+		r, err := l.readUnicodeEscape()
+		if err != nil {
+			return err
+		}
+		result.WriteRune(r)
+	default:
+		result.WriteRune(l.ch)
+		l.trackNewline()
+	}
+	return nil
+}
+
+// readUnicodeEscape reads the code point of a \uXXXX or \u{X...} escape,
+// l.ch being the u, and leaves l.ch on the last character of the escape.
+// A UTF-16 surrogate pair written as two escapes (\uD83D\uDE00) is combined.
+func (l *Lexer) readUnicodeEscape() (rune, error) {
+	r, ok := l.readCodePoint()
+	if !ok {
+		return 0, errInvalidUnicodeEscape
+	}
+	if !utf16.IsSurrogate(r) {
+		return r, nil
+	}
+	if !strings.HasPrefix(l.input[l.readPosition:], `\u`) {
+		return 0, errInvalidUnicodeEscape
+	}
+	l.readChar()
+	l.readChar()
+	low, ok := l.readCodePoint()
+	if pair := utf16.DecodeRune(r, low); ok && pair != utf8.RuneError {
+		return pair, nil
+	}
+	return 0, errInvalidUnicodeEscape
+}
+
+// readCodePoint reads the hexadecimal digits following a \u.
+func (l *Lexer) readCodePoint() (rune, bool) {
+	rest := l.input[l.readPosition:]
+	digits := rest
+	if strings.HasPrefix(rest, "{") {
+		end := strings.IndexByte(rest, '}')
+		if end < 2 || end > 7 {
+			return 0, false
+		}
+		digits = rest[1:end]
+		rest = rest[:end+1]
+	} else {
+		if len(rest) < 4 {
+			return 0, false
+		}
+		digits = rest[:4]
+		rest = digits
+	}
+	n, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil || n > unicode.MaxRune {
+		return 0, false
+	}
+	for i := 0; i < len(rest); i++ {
+		l.readChar()
+	}
+	return rune(n), true
+}
+
+// trackNewline updates the line and column when a string spans lines.
+func (l *Lexer) trackNewline() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+}
+
+// trimIndent drops the first line of a multiline raw string when it is
+// blank, the last line when it only holds the indentation of the closing
+// backtick, and the indentation common to the remaining non-blank lines.
+func trimIndent(s string) string {
+	if !strings.Contains(s, "\n") {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	if strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if n := len(lines); n > 0 && strings.TrimSpace(lines[n-1]) == "" {
+		lines = lines[:n-1]
+	}
+
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		width := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || width < indent {
+			indent = width
+		}
+	}
+	for i, line := range lines {
+		if len(line) >= indent {
+			lines[i] = line[max(indent, 0):]
+		} else {
+			lines[i] = ""
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isLetter reports whether ch can start an identifier: an underscore or any
+// Unicode letter, so names such as prénom or größe are valid.
+func isLetter(ch rune) bool {
+	if ch < utf8.RuneSelf {
+		return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+	}
+	return unicode.IsLetter(ch)
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
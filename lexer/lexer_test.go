@@ -56,7 +56,7 @@ if (x > 10) {
 }
 
 func TestOperators(t *testing.T) {
-	input := `== != < > <= >= && || ?: ?. ! + - * /`
+	input := `== != < > <= >= && || ?: ?. ! + - * / ? & | ^ ~ << >> **`
 
 	tests := []token.Type{
 		token.EQ,
@@ -74,6 +74,14 @@ func TestOperators(t *testing.T) {
 		token.MINUS,
 		token.ASTERISK,
 		token.SLASH,
+		token.QUESTION,
+		token.BIT_AND,
+		token.BIT_OR,
+		token.BIT_XOR,
+		token.BIT_NOT,
+		token.SHIFT_LEFT,
+		token.SHIFT_RIGHT,
+		token.POWER,
 		token.EOF,
 	}
 
@@ -88,16 +96,21 @@ func TestOperators(t *testing.T) {
 }
 
 func TestKeywords(t *testing.T) {
-	input := `let if else true false null return`
+	input := `let const if else true false null return break continue import export`
 
 	tests := []token.Type{
 		token.LET,
+		token.CONST,
 		token.IF,
 		token.ELSE,
 		token.TRUE,
 		token.FALSE,
 		token.NULL,
 		token.RETURN,
+		token.BREAK,
+		token.CONTINUE,
+		token.IMPORT,
+		token.EXPORT,
 		token.EOF,
 	}
 
@@ -130,6 +143,119 @@ func TestStringEscapes(t *testing.T) {
 	}
 }
 
+func TestStringForms(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`'single "quoted"'`, `single "quoted"`},
+		{`'it\'s'`, "it's"},
+		{`"a\rb\0c"`, "a\rb\x00c"},
+		{`"caf\u00e9"`, "café"},
+		{`"\u{1F600}!"`, "\U0001F600!"},
+		{`"\uD83D\uDE00"`, "\U0001F600"},
+		{"`raw \\n ${'$'}`", `raw \n ${'$'}`},
+		{"`one line`", "one line"},
+		{"`\n    SELECT *\n      FROM users\n    WHERE id = 1\n    `", "SELECT *\n  FROM users\nWHERE id = 1"},
+		{"`first\n  second`", "first\n  second"},
+		{"`\n  a\n\n  b\n`", "a\n\nb"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if (tok.Type != token.STRING && tok.Type != token.STRING_TEMPLATE) || tok.Literal != tt.expected {
+			t.Errorf("%s: expected %q, got %s %q", tt.input, tt.expected, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestRawStringTemplate(t *testing.T) {
+	l := New("`\n  Hello ${name},\n  total: ${total}\n`")
+	tok := l.NextToken()
+	if tok.Type != token.STRING_TEMPLATE || tok.Literal != "Hello ${name},\ntotal: ${total}" {
+		t.Fatalf("expected STRING_TEMPLATE, got %s %q", tok.Type, tok.Literal)
+	}
+}
+
+func TestStringErrors(t *testing.T) {
+	tests := []struct {
+		input   string
+		message string
+		line    int
+		column  int
+	}{
+		{"let s = \"abc", "unterminated string", 1, 9},
+		{"x\nlet s = 'abc\ndef", "unterminated string", 2, 9},
+		{"let s = `abc\n", "unterminated string", 1, 9},
+		{`"\u12"`, "invalid unicode escape", 1, 1},
+		{`"\u{110000}"`, "invalid unicode escape", 1, 1},
+		{`"\uD83D"`, "invalid unicode escape", 1, 1},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		for tok.Type != token.ILLEGAL && tok.Type != token.EOF {
+			tok = l.NextToken()
+		}
+		if tok.Type != token.ILLEGAL || tok.Literal != tt.message {
+			t.Errorf("%q: expected ILLEGAL %q, got %s %q", tt.input, tt.message, tok.Type, tok.Literal)
+			continue
+		}
+		if tok.Line != tt.line || tok.Column != tt.column {
+			t.Errorf("%q: expected position %d:%d, got %d:%d", tt.input, tt.line, tt.column, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestMultilineStringLines(t *testing.T) {
+	l := New("let s = `a\nb`\nx")
+	var last token.Token
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		last = tok
+	}
+	if last.Literal != "x" || last.Line != 3 {
+		t.Errorf("expected x on line 3, got %q on line %d", last.Literal, last.Line)
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	input := "let prénom = \"Zoë\"\nlet größe_2 = prénom + \"é\" // café\nπ"
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+		expectedColumn  int
+	}{
+		{token.LET, "let", 1},
+		{token.IDENT, "prénom", 5},
+		{token.ASSIGN, "=", 12},
+		{token.STRING, "Zoë", 14},
+		{token.NEWLINE, "\\n", 19},
+		{token.LET, "let", 1},
+		{token.IDENT, "größe_2", 5},
+		{token.ASSIGN, "=", 13},
+		{token.IDENT, "prénom", 15},
+		{token.PLUS, "+", 22},
+		{token.STRING, "é", 24},
+		{token.NEWLINE, "\\n", 35},
+		{token.IDENT, "π", 1},
+		{token.EOF, "", 2},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - expected %s %q, got %s %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+		if tok.Column != tt.expectedColumn {
+			t.Errorf("tests[%d] - %q: expected column %d, got %d", i, tok.Literal, tt.expectedColumn, tok.Column)
+		}
+	}
+}
+
 func TestNumbers(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -138,6 +264,23 @@ func TestNumbers(t *testing.T) {
 		{"42", "42"},
 		{"3.14", "3.14"},
 		{"100.0", "100.0"},
+		{"19.99d", "19.99d"},
+		{"5d", "5d"},
+		{"1e6", "1e6"},
+		{"2.5E-3", "2.5E-3"},
+		{"1e+10d", "1e+10d"},
+		{"0xFF", "0xFF"},
+		{"0o17", "0o17"},
+		{"0b1010", "0b1010"},
+		{"1_000_000", "1_000_000"},
+		{"1..5", "1"},
+		{"0x1F - 1", "0x1F"},
+		{"1e-3-1", "1e-3"},
+		// Malformed numbers are read whole and rejected by the parser
+		{"5days", "5days"},
+		{"0b102", "0b102"},
+		{"1e", "1e"},
+		{"1e+", "1e+"},
 	}
 
 	for _, tt := range tests {
@@ -149,6 +292,32 @@ func TestNumbers(t *testing.T) {
 	}
 }
 
+func TestMisplacedDecimalPoint(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1.2.3 + 1", "malformed number literal 1.2.3: a number has a single decimal point"},
+		{"1.", "malformed number literal 1.: '.' must be followed by a digit"},
+		{"2.x", "malformed number literal 2.: '.' must be followed by a digit"},
+	}
+
+	for _, tt := range tests {
+		tok := New(tt.input).NextToken()
+		if tok.Type != token.ILLEGAL || tok.Literal != tt.expected {
+			t.Errorf("%q: expected ILLEGAL %q, got %q %q", tt.input, tt.expected, tok.Type, tok.Literal)
+		}
+	}
+
+	// A range after a float is still two numbers around ..
+	l := New("1.5..3")
+	for _, expected := range []string{"1.5", "..", "3"} {
+		if tok := l.NextToken(); tok.Literal != expected {
+			t.Fatalf("expected %q, got %q", expected, tok.Literal)
+		}
+	}
+}
+
 func TestComments(t *testing.T) {
 	input := `let x = 1 // this is a comment
 let y = 2`
@@ -178,6 +347,77 @@ let y = 2`
 	}
 }
 
+func TestBlockComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Type
+	}{
+		{"let x = /* inline */ 1", []token.Type{token.LET, token.IDENT, token.ASSIGN, token.NUMBER, token.EOF}},
+		{"/* outer /* inner */ still outer */ x", []token.Type{token.IDENT, token.EOF}},
+		{"x /* spans\nlines */ y", []token.Type{token.IDENT, token.NEWLINE, token.IDENT, token.EOF}},
+		{"x + /* spans\nlines */ y", []token.Type{token.IDENT, token.PLUS, token.IDENT, token.EOF}},
+		{"a /**/ / b", []token.Type{token.IDENT, token.SLASH, token.IDENT, token.EOF}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for i, expected := range tt.expected {
+			tok := l.NextToken()
+			if tok.Type != expected {
+				t.Errorf("%q: token %d: expected %s, got %s %q", tt.input, i, expected, tok.Type, tok.Literal)
+				break
+			}
+		}
+	}
+}
+
+func TestBlockCommentPositions(t *testing.T) {
+	l := New("/* one\n   two */ let")
+	tok := l.NextToken()
+	if tok.Type != token.LET || tok.Line != 2 || tok.Column != 11 {
+		t.Errorf("expected LET at 2:11, got %s at %d:%d", tok.Type, tok.Line, tok.Column)
+	}
+
+	l = New("x\n  /* never /* closed */")
+	for tok = l.NextToken(); tok.Type != token.ILLEGAL && tok.Type != token.EOF; tok = l.NextToken() {
+	}
+	if tok.Type != token.ILLEGAL || tok.Literal != "unterminated comment" || tok.Line != 2 || tok.Column != 3 {
+		t.Errorf("expected unterminated comment at 2:3, got %s %q at %d:%d", tok.Type, tok.Literal, tok.Line, tok.Column)
+	}
+}
+
+func TestDocComments(t *testing.T) {
+	input := `/// Applies VAT.
+///
+///   rate: a fraction
+fn ttc(x, rate) { x }
+//// banner, not documentation
+let a = 1 // plain comment
+/// Default rate.
+let rate = 0.2`
+
+	docs := map[string]string{}
+	l := New(input)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Doc != "" {
+			docs[tok.Literal] = tok.Doc
+		}
+	}
+
+	expected := map[string]string{
+		"fn":  "Applies VAT.\n\n  rate: a fraction",
+		"let": "Default rate.",
+	}
+	if len(docs) != len(expected) {
+		t.Fatalf("expected docs %q, got %q", expected, docs)
+	}
+	for lit, doc := range expected {
+		if docs[lit] != doc {
+			t.Errorf("doc of %s: expected %q, got %q", lit, doc, docs[lit])
+		}
+	}
+}
+
 func TestDelimiters(t *testing.T) {
 	input := `() {} , ; .`
 
@@ -232,3 +472,93 @@ func TestLineContinuation(t *testing.T) {
 		t.Fatalf("expected NUMBER after continuation, got %q", tok3.Type)
 	}
 }
+
+func TestMatchAndRangeTokens(t *testing.T) {
+	input := `match -> 1..5 1..<5 -x ...rest x => y`
+
+	tests := []token.Type{
+		token.MATCH,
+		token.ARROW,
+		token.NUMBER,
+		token.DOT_DOT,
+		token.NUMBER,
+		token.NUMBER,
+		token.DOT_DOT_LT,
+		token.NUMBER,
+		token.MINUS,
+		token.IDENT,
+		token.ELLIPSIS,
+		token.IDENT,
+		token.IDENT,
+		token.FAT_ARROW,
+		token.IDENT,
+		token.EOF,
+	}
+
+	l := New(input)
+
+	for i, expected := range tests {
+		tok := l.NextToken()
+		if tok.Type != expected {
+			t.Fatalf("tests[%d] - expected=%q, got=%q", i, expected, tok.Type)
+		}
+	}
+}
+
+func TestUpdateOrSigns(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Type
+	}{
+		{"x--", []token.Type{token.IDENT, token.DECREMENT, token.EOF}},
+		{"arr[0]++\n", []token.Type{token.IDENT, token.LBRACKET, token.NUMBER, token.RBRACKET, token.INCREMENT, token.NEWLINE, token.EOF}},
+		{"--x", []token.Type{token.DECREMENT, token.IDENT, token.EOF}},
+		{"5--3", []token.Type{token.NUMBER, token.MINUS, token.MINUS, token.NUMBER, token.EOF}},
+		{"a--b", []token.Type{token.IDENT, token.MINUS, token.MINUS, token.IDENT, token.EOF}},
+		{"a++ (b)", []token.Type{token.IDENT, token.PLUS, token.PLUS, token.LPAREN, token.IDENT, token.RPAREN, token.EOF}},
+		{"y = --3", []token.Type{token.IDENT, token.ASSIGN, token.MINUS, token.MINUS, token.NUMBER, token.EOF}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for i, expected := range tt.expected {
+			tok := l.NextToken()
+			if tok.Type != expected {
+				t.Errorf("%q: token %d: expected %s, got %s", tt.input, i, expected, tok.Type)
+				break
+			}
+		}
+	}
+}
+
+func TestCompoundAssignmentTokens(t *testing.T) {
+	input := `+= -= *= /= %= x++; --x + - * / %`
+
+	tests := []token.Type{
+		token.PLUS_ASSIGN,
+		token.MINUS_ASSIGN,
+		token.ASTERISK_ASSIGN,
+		token.SLASH_ASSIGN,
+		token.PERCENT_ASSIGN,
+		token.IDENT,
+		token.INCREMENT,
+		token.SEMICOLON,
+		token.DECREMENT,
+		token.IDENT,
+		token.PLUS,
+		token.MINUS,
+		token.ASTERISK,
+		token.SLASH,
+		token.PERCENT,
+		token.EOF,
+	}
+
+	l := New(input)
+
+	for i, expected := range tests {
+		tok := l.NextToken()
+		if tok.Type != expected {
+			t.Fatalf("tests[%d] - expected=%q, got=%q", i, expected, tok.Type)
+		}
+	}
+}
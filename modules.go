@@ -0,0 +1,71 @@
+package kodi
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/issadicko/kodi-script-go/ast"
+)
+
+// ModuleResolver loads the source code of modules imported by a script with
+// import { name } from "module". Implementations can read from the
+// filesystem, an embed.FS, a database, etc.
+type ModuleResolver interface {
+	ResolveModule(name string) (string, error)
+}
+
+// ModuleResolverFunc adapts a function to the ModuleResolver interface.
+type ModuleResolverFunc func(name string) (string, error)
+
+// ResolveModule calls f(name).
+func (f ModuleResolverFunc) ResolveModule(name string) (string, error) {
+	return f(name)
+}
+
+// FSResolver resolves modules from a file system such as os.DirFS or an
+// embed.FS. A module name without extension gets the .kodi extension, so
+// "billing/tax" is read from billing/tax.kodi.
+type FSResolver struct {
+	FS fs.FS
+}
+
+// NewFSResolver creates a resolver reading modules from fsys.
+func NewFSResolver(fsys fs.FS) *FSResolver {
+	return &FSResolver{FS: fsys}
+}
+
+// ResolveModule reads the source of the named module.
+func (r *FSResolver) ResolveModule(name string) (string, error) {
+	file := strings.TrimPrefix(path.Clean(name), "./")
+	if path.Ext(file) == "" {
+		file += ".kodi"
+	}
+	data, err := fs.ReadFile(r.FS, file)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// WithModuleResolver enables import statements, loading imported modules
+// through resolver. Each module is parsed once (sharing the AST cache) and
+// evaluated once per execution; import cycles are reported as errors.
+func (s *Script) WithModuleResolver(resolver ModuleResolver) *Script {
+	s.resolver = resolver
+	return s
+}
+
+// loadModule resolves and parses a module for the interpreter.
+func (s *Script) loadModule(name string) (*ast.Program, error) {
+	source, err := s.resolver.ResolveModule(name)
+	if err != nil {
+		return nil, err
+	}
+	program, errs := s.parse(source)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return program, nil
+}
@@ -0,0 +1,175 @@
+package kodi
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// mapResolver serves module sources from a map.
+func mapResolver(modules map[string]string) ModuleResolver {
+	return ModuleResolverFunc(func(name string) (string, error) {
+		source, ok := modules[name]
+		if !ok {
+			return "", fmt.Errorf("module not found")
+		}
+		return source, nil
+	})
+}
+
+func TestImportModule(t *testing.T) {
+	resolver := mapResolver(map[string]string{
+		"billing/tax": `const RATE = 0.2
+export fn calcTax(amount) { return amount * RATE }
+export const currency = "EUR"
+export let {label, code = "TVA"} = {label: "Taxe"}`,
+	})
+
+	result := New(`import { calcTax, currency as cur, code } from "billing/tax"
+calcTax(100) + " " + cur + " " + code`).WithModuleResolver(resolver).Execute()
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if result.Value != "20.0 EUR TVA" {
+		t.Errorf("expected '20.0 EUR TVA', got %v", result.Value)
+	}
+}
+
+func TestImportEvaluatesModuleOnce(t *testing.T) {
+	resolver := mapResolver(map[string]string{
+		"counter": `print("loading counter")
+let count = 0
+export fn next() {
+    count += 1
+    return count
+}`,
+		"helpers": `import { next } from "counter"
+export fn twice() { next()
+return next() }`,
+	})
+
+	result := New(`import { next } from "counter"
+import { twice } from "helpers"
+next()
+twice()`).WithModuleResolver(resolver).SilentPrint(true).Execute()
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	// Both importers share the same module instance
+	if result.Value != int64(3) {
+		t.Errorf("expected 3, got %v", result.Value)
+	}
+	if len(result.Output) != 1 || result.Output[0] != "loading counter" {
+		t.Errorf("expected the module to be evaluated once, got output %v", result.Output)
+	}
+}
+
+func TestImportingScriptRunsTwice(t *testing.T) {
+	resolver := mapResolver(map[string]string{
+		"math": `export fn square(x) { return x * x }`,
+	})
+
+	script := New(`import { square } from "math"
+square(4)`).WithModuleResolver(resolver)
+	for run := 1; run <= 2; run++ {
+		result := script.Execute()
+		if len(result.Errors) > 0 {
+			t.Fatalf("run %d: unexpected errors: %v", run, result.Errors)
+		}
+		if result.Value != int64(16) {
+			t.Errorf("run %d: expected 16, got %v", run, result.Value)
+		}
+	}
+}
+
+func TestImportCycle(t *testing.T) {
+	resolver := mapResolver(map[string]string{
+		"a": `import { b } from "b"
+export fn a() { return 1 }`,
+		"b": `import { a } from "a"
+export fn b() { return 2 }`,
+	})
+
+	result := New(`import { a } from "a"`).WithModuleResolver(resolver).Execute()
+	if len(result.Errors) == 0 {
+		t.Fatal("expected an import cycle error")
+	}
+	if result.Errors[0] != "import cycle: a -> b -> a" {
+		t.Errorf("unexpected error: %s", result.Errors[0])
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	resolver := mapResolver(map[string]string{
+		"math":   `export fn square(x) { return x * x }`,
+		"broken": `let = 1`,
+	})
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{`import { cube } from "math"`, `module "math" does not export 'cube'`},
+		{`import { x } from "missing"`, `cannot load module "missing": module not found`},
+		{`import { x } from "broken"`, `cannot load module "broken": line 1, col 1: expected IDENT`},
+		{`let secret = 1
+import { square } from "math"
+square = null`, "cannot assign to constant 'square'"},
+	}
+
+	for _, tt := range tests {
+		result := New(tt.source).WithModuleResolver(resolver).Execute()
+		if len(result.Errors) == 0 {
+			t.Fatalf("'%s': expected error", tt.source)
+		}
+		if !strings.Contains(result.Errors[0], tt.expected) {
+			t.Errorf("'%s': expected error containing %q, got %q", tt.source, tt.expected, result.Errors[0])
+		}
+	}
+
+	result := New(`import { square } from "math"`).Execute()
+	if len(result.Errors) == 0 || !strings.Contains(result.Errors[0], "no module resolver configured") {
+		t.Errorf("expected missing resolver error, got %v", result.Errors)
+	}
+}
+
+func TestModulesAreIsolated(t *testing.T) {
+	resolver := mapResolver(map[string]string{
+		"peek": `export fn peek() { return secret }`,
+	})
+
+	result := New(`let secret = 42
+import { peek } from "peek"
+try {
+    peek()
+} catch (e) {
+    e.kind
+}`).WithModuleResolver(resolver).Execute()
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if result.Value != "ReferenceError" {
+		t.Errorf("expected 'ReferenceError', got %v", result.Value)
+	}
+}
+
+func TestFSResolver(t *testing.T) {
+	fsys := fstest.MapFS{
+		"billing/tax.kodi":  {Data: []byte(`export fn calcTax(amount) { return amount * 0.1 }`)},
+		"lib/strings.kodi":  {Data: []byte(`export fn shout(s) { return toUpperCase(s) + "!" }`)},
+		"lib/readme.txt":    {Data: []byte(`not a module`)},
+		"lib/explicit.kodi": {Data: []byte(`export const answer = 42`)},
+	}
+
+	result := New(`import { calcTax } from "billing/tax"
+import { shout } from "./lib/strings"
+import { answer } from "lib/explicit.kodi"
+shout("total " + calcTax(50) + " " + answer)`).WithModuleResolver(NewFSResolver(fsys)).Execute()
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if result.Value != "TOTAL 5.0 42!" {
+		t.Errorf("expected 'TOTAL 5.0 42!', got %v", result.Value)
+	}
+}
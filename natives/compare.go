@@ -0,0 +1,116 @@
+package natives
+
+import (
+	"cmp"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/issadicko/kodi-script-go/convert"
+	"github.com/issadicko/kodi-script-go/decimal"
+)
+
+// Collation orders two strings like strings.Compare: negative when a sorts
+// before b, zero when they are equivalent and positive otherwise. Hosts can
+// plug in a locale-aware comparison, such as collate.Collator.CompareString
+// from golang.org/x/text.
+type Collation func(a, b string) int
+
+// Compare orders two values the way the < > <= >= operators, sort, sortBy,
+// min and max do:
+//   - numbers by value, integers and decimals exactly
+//   - strings with collate, or byte-wise when collate is nil
+//   - booleans with false before true
+//   - Go values with a Compare method accepting the other value and
+//     returning an int, such as time.Time
+//
+// Values without a common order, null included, return an error.
+func Compare(a, b interface{}, collate Collation) (int, error) {
+	a, b = convert.Normalize(a), convert.Normalize(b)
+
+	switch av := a.(type) {
+	case int64:
+		if bv, ok := b.(int64); ok {
+			return cmp.Compare(av, bv), nil
+		}
+	case string:
+		if bv, ok := b.(string); ok {
+			if collate != nil {
+				return collate(av, bv), nil
+			}
+			return strings.Compare(av, bv), nil
+		}
+	case bool:
+		if bv, ok := b.(bool); ok {
+			return compareBools(av, bv), nil
+		}
+	}
+
+	_, aDecimal := a.(*big.Rat)
+	_, bDecimal := b.(*big.Rat)
+	if aDecimal || bDecimal {
+		if ad, ok := decimal.From(a); ok {
+			if bd, ok := decimal.From(b); ok {
+				return ad.Cmp(bd), nil
+			}
+		}
+	}
+	if ab, ok := convert.BigInteger(a); ok {
+		if bb, ok := convert.BigInteger(b); ok {
+			return ab.Cmp(bb), nil
+		}
+	}
+	if af, ok := toFloat(a); ok {
+		if bf, ok := toFloat(b); ok {
+			return cmp.Compare(af, bf), nil
+		}
+	}
+	if c, ok := compareMethod(a, b); ok {
+		return c, nil
+	}
+	return 0, fmt.Errorf("cannot compare %s and %s", typeName(a), typeName(b))
+}
+
+func compareBools(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case b:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// compareMethod orders a and b with a's Compare method when it takes b
+// (or the value b points to) and returns an int.
+func compareMethod(a, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	method := reflect.ValueOf(a).MethodByName("Compare")
+	if !method.IsValid() {
+		return 0, false
+	}
+	mt := method.Type()
+	if mt.NumIn() != 1 || mt.NumOut() != 1 || mt.Out(0).Kind() != reflect.Int {
+		return 0, false
+	}
+	arg := reflect.ValueOf(b)
+	if !arg.Type().AssignableTo(mt.In(0)) {
+		if arg.Kind() != reflect.Ptr || arg.IsNil() || !arg.Elem().Type().AssignableTo(mt.In(0)) {
+			return 0, false
+		}
+		arg = arg.Elem()
+	}
+	return int(method.Call([]reflect.Value{arg})[0].Int()), true
+}
+
+// typeName names the type of a value in comparison errors.
+func typeName(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -10,11 +10,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"math/big"
 	"math/rand"
 	"net/url"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/issadicko/kodi-script-go/convert"
+	"github.com/issadicko/kodi-script-go/decimal"
 )
 
 func init() {
@@ -26,7 +33,24 @@ type NativeFunc func(args ...interface{}) (interface{}, error)
 
 // Registry holds all registered native functions.
 type Registry struct {
-	funcs map[string]NativeFunc
+	funcs     map[string]NativeFunc
+	collation Collation // orders strings in the collated builtins, nil for byte order
+}
+
+// collatedFunc is a builtin that orders strings with the collation of the
+// registry it is looked up from.
+type collatedFunc func(collate Collation, args []interface{}) (interface{}, error)
+
+// collatedBuiltins are the builtins that depend on Registry.SetCollation.
+var collatedBuiltins = map[string]collatedFunc{
+	"sort":   sortValues,
+	"sortBy": sortValuesBy,
+	"min": func(collate Collation, args []interface{}) (interface{}, error) {
+		return extremeValue("min", -1, collate, args)
+	},
+	"max": func(collate Collation, args []interface{}) (interface{}, error) {
+		return extremeValue("max", 1, collate, args)
+	},
 }
 
 // DefaultBuiltins is a global read-only registry containing all built-in functions.
@@ -51,6 +75,14 @@ func (r *Registry) Get(name string) NativeFunc {
 	if fn, ok := r.funcs[name]; ok {
 		return fn // Custom takes priority
 	}
+	if r.collation != nil {
+		if fn, ok := collatedBuiltins[name]; ok {
+			collate := r.collation
+			return func(args ...interface{}) (interface{}, error) {
+				return fn(collate, args)
+			}
+		}
+	}
 	// Fallback to global builtins
 	if r != DefaultBuiltins {
 		return DefaultBuiltins.funcs[name]
@@ -63,10 +95,20 @@ func (r *Registry) Register(name string, fn NativeFunc) {
 	r.funcs[name] = fn
 }
 
+// SetCollation makes the builtin sort, sortBy, min and max looked up from
+// this registry order strings with collate. Custom functions registered
+// under those names keep priority.
+func (r *Registry) SetCollation(collate Collation) {
+	r.collation = collate
+}
+
 func (r *Registry) registerBuiltins() {
 	// String functions
 	r.funcs["toString"] = nativeToString
 	r.funcs["toNumber"] = nativeToNumber
+	r.funcs["int"] = nativeInt
+	r.funcs["float"] = nativeFloat
+	r.funcs["decimal"] = nativeDecimal
 	r.funcs["length"] = nativeLength
 	r.funcs["substring"] = nativeSubstring
 	r.funcs["toUpperCase"] = nativeToUpperCase
@@ -162,7 +204,7 @@ func nativeToString(args ...interface{}) (interface{}, error) {
 	if len(args) != 1 {
 		return nil, fmt.Errorf("toString requires 1 argument")
 	}
-	return fmt.Sprintf("%v", args[0]), nil
+	return formatValue(args[0]), nil
 }
 
 func nativeToNumber(args ...interface{}) (interface{}, error) {
@@ -170,7 +212,7 @@ func nativeToNumber(args ...interface{}) (interface{}, error) {
 		return nil, fmt.Errorf("toNumber requires 1 argument")
 	}
 	switch v := args[0].(type) {
-	case float64:
+	case float64, int64, *big.Rat:
 		return v, nil
 	case int:
 		return float64(v), nil
@@ -185,12 +227,90 @@ func nativeToNumber(args ...interface{}) (interface{}, error) {
 	}
 }
 
+// nativeInt converts a number or a numeric string to an integer, truncating decimals.
+func nativeInt(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("int requires 1 argument")
+	}
+	var f float64
+	switch v := args[0].(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case float64:
+		f = v
+	case *big.Rat:
+		n := new(big.Int).Quo(v.Num(), v.Denom())
+		if !n.IsInt64() {
+			return nil, fmt.Errorf("cannot convert %s to int: out of range", decimal.Format(v))
+		}
+		return n.Int64(), nil
+	case string:
+		s := strings.TrimSpace(v)
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, nil
+		}
+		parsed, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert '%s' to int", v)
+		}
+		f = parsed
+	default:
+		return nil, fmt.Errorf("cannot convert %T to int", args[0])
+	}
+	if math.IsNaN(f) || f >= math.MaxInt64 || f < math.MinInt64 {
+		return nil, fmt.Errorf("cannot convert %v to int: out of range", f)
+	}
+	return int64(f), nil
+}
+
+// nativeFloat converts a number or a numeric string to a float.
+func nativeFloat(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("float requires 1 argument")
+	}
+	if s, ok := args[0].(string); ok {
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert '%s' to float", s)
+		}
+		return f, nil
+	}
+	f, ok := toFloat(args[0])
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %T to float", args[0])
+	}
+	return f, nil
+}
+
+// nativeDecimal converts a number or a numeric string to an exact decimal.
+// Floats are converted from their shortest representation: decimal(0.1) is 1/10.
+func nativeDecimal(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("decimal requires 1 argument")
+	}
+	if s, ok := args[0].(string); ok {
+		s = strings.TrimSpace(s)
+		d, ok := new(big.Rat).SetString(s)
+		if !ok || strings.Contains(s, "/") {
+			return nil, fmt.Errorf("cannot convert '%s' to decimal", args[0])
+		}
+		return d, nil
+	}
+	d, ok := decimal.From(args[0])
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %v to decimal", args[0])
+	}
+	return d, nil
+}
+
 func nativeLength(args ...interface{}) (interface{}, error) {
 	if len(args) != 1 {
 		return nil, fmt.Errorf("length requires 1 argument")
 	}
 	if s, ok := args[0].(string); ok {
-		return float64(len(s)), nil
+		return int64(utf16Len(s)), nil
 	}
 	return nil, fmt.Errorf("length requires a string argument")
 }
@@ -203,31 +323,36 @@ func nativeSubstring(args ...interface{}) (interface{}, error) {
 	if !ok {
 		return nil, fmt.Errorf("substring requires a string as first argument")
 	}
-	start, ok := args[1].(float64)
+	start, ok := toFloat(args[1])
 	if !ok {
 		return nil, fmt.Errorf("substring requires a number as second argument")
 	}
+	// Indices count UTF-16 code units, not bytes
+	chars := utf16.Encode([]rune(s))
 	startIdx := int(start)
 	if startIdx < 0 {
 		startIdx = 0
 	}
-	if startIdx >= len(s) {
+	if startIdx >= len(chars) {
 		return "", nil
 	}
 
 	if len(args) == 3 {
-		end, ok := args[2].(float64)
+		end, ok := toFloat(args[2])
 		if !ok {
 			return nil, fmt.Errorf("substring requires a number as third argument")
 		}
 		endIdx := int(end)
-		if endIdx > len(s) {
-			endIdx = len(s)
+		if endIdx > len(chars) {
+			endIdx = len(chars)
 		}
-		return s[startIdx:endIdx], nil
+		if endIdx <= startIdx {
+			return "", nil
+		}
+		return string(utf16.Decode(chars[startIdx:endIdx])), nil
 	}
 
-	return s[startIdx:], nil
+	return string(utf16.Decode(chars[startIdx:])), nil
 }
 
 func nativeToUpperCase(args ...interface{}) (interface{}, error) {
@@ -284,7 +409,7 @@ func nativeJoin(args ...interface{}) (interface{}, error) {
 	if len(args) != 2 {
 		return nil, fmt.Errorf("join requires 2 arguments")
 	}
-	arr, ok := args[0].([]interface{})
+	arr, ok := convert.ToArray(args[0])
 	if !ok {
 		return nil, fmt.Errorf("join requires an array as first argument")
 	}
@@ -294,7 +419,7 @@ func nativeJoin(args ...interface{}) (interface{}, error) {
 	}
 	strs := make([]string, len(arr))
 	for i, v := range arr {
-		strs[i] = fmt.Sprintf("%v", v)
+		strs[i] = formatValue(v)
 	}
 	return strings.Join(strs, sep), nil
 }
@@ -375,24 +500,25 @@ func nativeIndexOf(args ...interface{}) (interface{}, error) {
 	if !ok {
 		return nil, fmt.Errorf("indexOf requires a string as second argument")
 	}
-	return float64(strings.Index(s, substr)), nil
+	i := strings.Index(s, substr)
+	if i < 0 {
+		return int64(-1), nil
+	}
+	return int64(utf16Len(s[:i])), nil
 }
 
 func nativePadLeft(args ...interface{}) (interface{}, error) {
 	if len(args) < 2 {
 		return nil, fmt.Errorf("padLeft requires at least 2 arguments")
 	}
-	s := fmt.Sprintf("%v", args[0])
+	s := formatValue(args[0])
 	length := int(asFloat(args[1]))
 	padChar := " "
 	if len(args) > 2 && args[2] != nil {
-		padChar = fmt.Sprintf("%v", args[2])
-		if len(padChar) == 0 {
-			padChar = " "
-		}
+		padChar = firstChar(fmt.Sprintf("%v", args[2]))
 	}
-	for len(s) < length {
-		s = padChar[:1] + s
+	if missing := length - utf16Len(s); missing > 0 {
+		s = strings.Repeat(padChar, missing/utf16Len(padChar)) + s
 	}
 	return s, nil
 }
@@ -401,26 +527,47 @@ func nativePadRight(args ...interface{}) (interface{}, error) {
 	if len(args) < 2 {
 		return nil, fmt.Errorf("padRight requires at least 2 arguments")
 	}
-	s := fmt.Sprintf("%v", args[0])
+	s := formatValue(args[0])
 	length := int(asFloat(args[1]))
 	padChar := " "
 	if len(args) > 2 && args[2] != nil {
-		padChar = fmt.Sprintf("%v", args[2])
-		if len(padChar) == 0 {
-			padChar = " "
-		}
+		padChar = firstChar(fmt.Sprintf("%v", args[2]))
 	}
-	for len(s) < length {
-		s = s + padChar[:1]
+	if missing := length - utf16Len(s); missing > 0 {
+		s += strings.Repeat(padChar, missing/utf16Len(padChar))
 	}
 	return s, nil
 }
 
+// firstChar returns the first character of a padding string, or a space
+// when it is empty. A character outside the BMP counts as two code units,
+// and padding never goes past the requested length.
+func firstChar(s string) string {
+	if s == "" {
+		return " "
+	}
+	_, size := utf8.DecodeRuneInString(s)
+	return s[:size]
+}
+
+// utf16Len returns the length of s in UTF-16 code units, the unit the
+// Kotlin and TypeScript SDKs use for string lengths and positions.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n++
+		if r >= 0x10000 {
+			n++
+		}
+	}
+	return n
+}
+
 func nativeRepeat(args ...interface{}) (interface{}, error) {
 	if len(args) < 2 {
 		return nil, fmt.Errorf("repeat requires 2 arguments")
 	}
-	s := fmt.Sprintf("%v", args[0])
+	s := formatValue(args[0])
 	count := int(asFloat(args[1]))
 	if count < 0 {
 		count = 0
@@ -429,14 +576,8 @@ func nativeRepeat(args ...interface{}) (interface{}, error) {
 }
 
 func asFloat(v interface{}) float64 {
-	switch val := v.(type) {
-	case float64:
-		return val
-	case int:
-		return float64(val)
-	default:
-		return 0
-	}
+	f, _ := toFloat(v)
+	return f
 }
 
 // ============ JSON functions ============
@@ -460,13 +601,35 @@ func nativeJsonStringify(args ...interface{}) (interface{}, error) {
 	if len(args) != 1 {
 		return nil, fmt.Errorf("jsonStringify requires 1 argument")
 	}
-	b, err := json.Marshal(args[0])
+	b, err := json.Marshal(jsonDecimals(args[0]))
 	if err != nil {
 		return nil, fmt.Errorf("cannot stringify: %v", err)
 	}
 	return string(b), nil
 }
 
+// jsonDecimals returns v with its decimals replaced by JSON numbers, so that
+// they are written as 19.99 rather than as the fraction "1999/100".
+func jsonDecimals(v interface{}) interface{} {
+	switch val := v.(type) {
+	case *big.Rat:
+		return json.Number(decimal.Format(val))
+	case []interface{}:
+		result := make([]interface{}, len(val))
+		for i, item := range val {
+			result[i] = jsonDecimals(item)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			result[k] = jsonDecimals(item)
+		}
+		return result
+	}
+	return v
+}
+
 // ============ Base64 functions ============
 
 func nativeBase64Encode(args ...interface{}) (interface{}, error) {
@@ -538,6 +701,8 @@ func nativeTypeOf(args ...interface{}) (interface{}, error) {
 		return "string", nil
 	case float64, int, int64:
 		return "number", nil
+	case *big.Rat:
+		return "decimal", nil
 	case bool:
 		return "boolean", nil
 	case map[string]interface{}:
@@ -561,7 +726,7 @@ func nativeIsNumber(args ...interface{}) (interface{}, error) {
 		return nil, fmt.Errorf("isNumber requires 1 argument")
 	}
 	switch args[0].(type) {
-	case float64, int, int64:
+	case float64, int, int64, *big.Rat:
 		return true, nil
 	default:
 		return false, nil
@@ -590,6 +755,15 @@ func nativeAbs(args ...interface{}) (interface{}, error) {
 	if len(args) != 1 {
 		return nil, fmt.Errorf("abs requires 1 argument")
 	}
+	if i, ok := args[0].(int64); ok && i != math.MinInt64 {
+		if i < 0 {
+			return -i, nil
+		}
+		return i, nil
+	}
+	if d, ok := args[0].(*big.Rat); ok {
+		return new(big.Rat).Abs(d), nil
+	}
 	n, ok := toFloat(args[0])
 	if !ok {
 		return nil, fmt.Errorf("abs requires a number argument")
@@ -601,6 +775,9 @@ func nativeFloor(args ...interface{}) (interface{}, error) {
 	if len(args) != 1 {
 		return nil, fmt.Errorf("floor requires 1 argument")
 	}
+	if d, ok := args[0].(*big.Rat); ok {
+		return roundDecimal(d, 0, "floor"), nil
+	}
 	n, ok := toFloat(args[0])
 	if !ok {
 		return nil, fmt.Errorf("floor requires a number argument")
@@ -612,6 +789,9 @@ func nativeCeil(args ...interface{}) (interface{}, error) {
 	if len(args) != 1 {
 		return nil, fmt.Errorf("ceil requires 1 argument")
 	}
+	if d, ok := args[0].(*big.Rat); ok {
+		return roundDecimal(d, 0, "ceiling"), nil
+	}
 	n, ok := toFloat(args[0])
 	if !ok {
 		return nil, fmt.Errorf("ceil requires a number argument")
@@ -619,52 +799,133 @@ func nativeCeil(args ...interface{}) (interface{}, error) {
 	return math.Ceil(n), nil
 }
 
+// nativeRound rounds to a number of decimal places (0 by default, negative
+// to round to tens, hundreds...) with one of the roundingModes, half-up by
+// default. Decimals and integers keep their type; floats are rounded on their
+// shortest decimal representation, so round(2.675, 2) is 2.68.
 func nativeRound(args ...interface{}) (interface{}, error) {
-	if len(args) != 1 {
-		return nil, fmt.Errorf("round requires 1 argument")
+	if len(args) < 1 || len(args) > 3 {
+		return nil, fmt.Errorf("round requires 1 to 3 arguments")
+	}
+	places := 0
+	if len(args) > 1 {
+		p, ok := toFloat(args[1])
+		if !ok || p != math.Trunc(p) {
+			return nil, fmt.Errorf("round requires an integer number of places")
+		}
+		places = int(p)
 	}
-	n, ok := toFloat(args[0])
-	if !ok {
-		return nil, fmt.Errorf("round requires a number argument")
+	mode := "half-up"
+	if len(args) > 2 {
+		s, ok := args[2].(string)
+		if !ok || !roundingModes[s] {
+			return nil, fmt.Errorf("unknown rounding mode '%v'", args[2])
+		}
+		mode = s
 	}
-	return math.Round(n), nil
-}
 
-func nativeMin(args ...interface{}) (interface{}, error) {
-	if len(args) < 2 {
-		return nil, fmt.Errorf("min requires at least 2 arguments")
+	switch v := args[0].(type) {
+	case *big.Rat:
+		return roundDecimal(v, places, mode), nil
+	case int64:
+		if places >= 0 {
+			return v, nil
+		}
+		return roundDecimal(new(big.Rat).SetInt64(v), places, mode).Num().Int64(), nil
 	}
-	result, ok := toFloat(args[0])
+	n, ok := toFloat(args[0])
 	if !ok {
-		return nil, fmt.Errorf("min requires number arguments")
+		return nil, fmt.Errorf("round requires a number argument")
 	}
-	for i := 1; i < len(args); i++ {
-		n, ok := toFloat(args[i])
-		if !ok {
-			return nil, fmt.Errorf("min requires number arguments")
+	d, ok := decimal.From(n)
+	if !ok {
+		return n, nil // NaN and infinities
+	}
+	f, _ := roundDecimal(d, places, mode).Float64()
+	return f, nil
+}
+
+// roundingModes lists the modes accepted by round:
+//   - half-up: to nearest, ties away from zero
+//   - half-down: to nearest, ties toward zero
+//   - half-even: to nearest, ties to the even neighbour (banker's rounding)
+//   - up, down: away from zero, toward zero
+//   - ceiling, floor: toward positive, negative infinity
+var roundingModes = map[string]bool{
+	"half-up": true, "half-down": true, "half-even": true,
+	"up": true, "down": true, "ceiling": true, "floor": true,
+}
+
+// roundDecimal rounds d to places fraction digits with the given mode.
+func roundDecimal(d *big.Rat, places int, mode string) *big.Rat {
+	exp := places
+	if exp < 0 {
+		exp = -exp
+	}
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil))
+	scaled := new(big.Rat)
+	if places >= 0 {
+		scaled.Mul(d, scale)
+	} else {
+		scaled.Quo(d, scale)
+	}
+
+	// Truncate toward zero, then move away from zero if the mode asks for it
+	quo, rem := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	if rem.Sign() != 0 {
+		away := false
+		switch mode {
+		case "up":
+			away = true
+		case "ceiling":
+			away = d.Sign() > 0
+		case "floor":
+			away = d.Sign() < 0
+		case "half-up", "half-down", "half-even":
+			// Compare the dropped fraction |rem|/denom with one half
+			half := new(big.Int).Abs(rem)
+			half.Lsh(half, 1)
+			switch half.Cmp(scaled.Denom()) {
+			case 1:
+				away = true
+			case 0:
+				away = mode == "half-up" || (mode == "half-even" && quo.Bit(0) == 1)
+			}
 		}
-		if n < result {
-			result = n
+		if away {
+			quo.Add(quo, big.NewInt(int64(d.Sign())))
 		}
 	}
-	return result, nil
+
+	result := new(big.Rat).SetInt(quo)
+	if places >= 0 {
+		return result.Quo(result, scale)
+	}
+	return result.Mul(result, scale)
+}
+
+func nativeMin(args ...interface{}) (interface{}, error) {
+	return extremeValue("min", -1, nil, args)
 }
 
 func nativeMax(args ...interface{}) (interface{}, error) {
+	return extremeValue("max", 1, nil, args)
+}
+
+// extremeValue returns the smallest (sign -1) or largest (sign 1) of args in
+// the order of Compare. Numbers keep their type: min(2, 2.5) is the integer 2.
+func extremeValue(name string, sign int, collate Collation, args []interface{}) (interface{}, error) {
 	if len(args) < 2 {
-		return nil, fmt.Errorf("max requires at least 2 arguments")
+		return nil, fmt.Errorf("%s requires at least 2 arguments", name)
 	}
-	result, ok := toFloat(args[0])
-	if !ok {
-		return nil, fmt.Errorf("max requires number arguments")
-	}
-	for i := 1; i < len(args); i++ {
-		n, ok := toFloat(args[i])
-		if !ok {
-			return nil, fmt.Errorf("max requires number arguments")
+	result := args[0]
+	for _, arg := range args[1:] {
+		c, err := Compare(arg, result, collate)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
 		}
-		if n > result {
-			result = n
+		if c*sign > 0 {
+			result = arg
 		}
 	}
 	return result, nil
@@ -795,7 +1056,7 @@ func nativeRandomInt(args ...interface{}) (interface{}, error) {
 	if min >= max {
 		return nil, fmt.Errorf("randomInt: min must be less than max")
 	}
-	return float64(rand.Intn(int(max)-int(min)+1) + int(min)), nil
+	return int64(rand.Intn(int(max)-int(min)+1) + int(min)), nil
 }
 
 func nativeRandomUUID(args ...interface{}) (interface{}, error) {
@@ -858,18 +1119,31 @@ func toFloat(v interface{}) (float64, bool) {
 		return float64(n), true
 	case int64:
 		return float64(n), true
+	case *big.Rat:
+		f, _ := n.Float64()
+		return f, true
 	default:
 		return 0, false
 	}
 }
 
+// formatValue converts a value to a string the way print does.
+func formatValue(v interface{}) string {
+	return convert.String(v)
+}
+
 // ============ Array functions ============
 
 func nativeSort(args ...interface{}) (interface{}, error) {
+	return sortValues(nil, args)
+}
+
+// sortValues implements sort, ordering elements with compareValues.
+func sortValues(collate Collation, args []interface{}) (interface{}, error) {
 	if len(args) < 1 || len(args) > 2 {
 		return nil, fmt.Errorf("sort requires 1 or 2 arguments (array, [order])")
 	}
-	arr, ok := args[0].([]interface{})
+	arr, ok := convert.ToArray(args[0])
 	if !ok {
 		return nil, fmt.Errorf("sort requires an array as first argument")
 	}
@@ -893,7 +1167,7 @@ func nativeSort(args ...interface{}) (interface{}, error) {
 	// Sort using bubble sort (simple, works for mixed types)
 	for i := 0; i < len(result)-1; i++ {
 		for j := 0; j < len(result)-i-1; j++ {
-			should := compareValues(result[j], result[j+1])
+			should := compareValues(result[j], result[j+1], collate)
 			if ascending {
 				if should > 0 {
 					result[j], result[j+1] = result[j+1], result[j]
@@ -910,10 +1184,15 @@ func nativeSort(args ...interface{}) (interface{}, error) {
 }
 
 func nativeSortBy(args ...interface{}) (interface{}, error) {
+	return sortValuesBy(nil, args)
+}
+
+// sortValuesBy implements sortBy, ordering field values with compareValues.
+func sortValuesBy(collate Collation, args []interface{}) (interface{}, error) {
 	if len(args) < 2 || len(args) > 3 {
 		return nil, fmt.Errorf("sortBy requires 2 or 3 arguments (array, field, [order])")
 	}
-	arr, ok := args[0].([]interface{})
+	arr, ok := convert.ToArray(args[0])
 	if !ok {
 		return nil, fmt.Errorf("sortBy requires an array as first argument")
 	}
@@ -943,7 +1222,7 @@ func nativeSortBy(args ...interface{}) (interface{}, error) {
 		for j := 0; j < len(result)-i-1; j++ {
 			val1 := getFieldValue(result[j], field)
 			val2 := getFieldValue(result[j+1], field)
-			should := compareValues(val1, val2)
+			should := compareValues(val1, val2, collate)
 			if ascending {
 				if should > 0 {
 					result[j], result[j+1] = result[j+1], result[j]
@@ -963,7 +1242,7 @@ func nativeReverse(args ...interface{}) (interface{}, error) {
 	if len(args) != 1 {
 		return nil, fmt.Errorf("reverse requires 1 argument")
 	}
-	arr, ok := args[0].([]interface{})
+	arr, ok := convert.ToArray(args[0])
 	if !ok {
 		return nil, fmt.Errorf("reverse requires an array argument")
 	}
@@ -981,13 +1260,21 @@ func nativeSize(args ...interface{}) (interface{}, error) {
 	}
 	switch v := args[0].(type) {
 	case []interface{}:
-		return float64(len(v)), nil
+		return int64(len(v)), nil
 	case string:
-		return float64(len(v)), nil
+		return int64(utf16Len(v)), nil
 	case map[string]interface{}:
-		return float64(len(v)), nil
+		return int64(len(v)), nil
+	case convert.Sequence:
+		return int64(v.Len()), nil
+	}
+	// Go slices, arrays and maps bound by the host
+	rv := reflect.Indirect(reflect.ValueOf(args[0]))
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return int64(rv.Len()), nil
 	default:
-		return nil, fmt.Errorf("size requires an array, string, or object")
+		return nil, fmt.Errorf("size requires an array, range, string, or object")
 	}
 }
 
@@ -995,7 +1282,7 @@ func nativeFirst(args ...interface{}) (interface{}, error) {
 	if len(args) != 1 {
 		return nil, fmt.Errorf("first requires 1 argument")
 	}
-	arr, ok := args[0].([]interface{})
+	arr, ok := convert.ToArray(args[0])
 	if !ok {
 		return nil, fmt.Errorf("first requires an array argument")
 	}
@@ -1009,7 +1296,7 @@ func nativeLast(args ...interface{}) (interface{}, error) {
 	if len(args) != 1 {
 		return nil, fmt.Errorf("last requires 1 argument")
 	}
-	arr, ok := args[0].([]interface{})
+	arr, ok := convert.ToArray(args[0])
 	if !ok {
 		return nil, fmt.Errorf("last requires an array argument")
 	}
@@ -1023,7 +1310,7 @@ func nativeSlice(args ...interface{}) (interface{}, error) {
 	if len(args) < 2 || len(args) > 3 {
 		return nil, fmt.Errorf("slice requires 2 or 3 arguments (array, start, [end])")
 	}
-	arr, ok := args[0].([]interface{})
+	arr, ok := convert.ToArray(args[0])
 	if !ok {
 		return nil, fmt.Errorf("slice requires an array as first argument")
 	}
@@ -1057,8 +1344,10 @@ func nativeSlice(args ...interface{}) (interface{}, error) {
 	return arr[startIdx:], nil
 }
 
-// Helper: compare two values
-func compareValues(a, b interface{}) int {
+// Helper: compare two values for sorting. Nulls sort first, and values
+// without a common order (a number and a string) fall back to comparing
+// their string forms, so that sort never fails.
+func compareValues(a, b interface{}, collate Collation) int {
 	// Handle nil
 	if a == nil && b == nil {
 		return 0
@@ -1070,23 +1359,10 @@ func compareValues(a, b interface{}) int {
 		return 1
 	}
 
-	// Try numeric comparison
-	aNum, aOk := toFloat(a)
-	bNum, bOk := toFloat(b)
-	if aOk && bOk {
-		if aNum < bNum {
-			return -1
-		}
-		if aNum > bNum {
-			return 1
-		}
-		return 0
+	if c, err := Compare(a, b, collate); err == nil {
+		return c
 	}
-
-	// Try string comparison
-	aStr := fmt.Sprintf("%v", a)
-	bStr := fmt.Sprintf("%v", b)
-	return strings.Compare(aStr, bStr)
+	return strings.Compare(formatValue(a), formatValue(b))
 }
 
 // Helper: get field value from object
@@ -1094,13 +1370,25 @@ func getFieldValue(obj interface{}, field string) interface{} {
 	if m, ok := obj.(map[string]interface{}); ok {
 		return m[field]
 	}
-	return nil
+	// Exported fields of Go structs, e.g. when sorting a bound []User
+	rv := reflect.ValueOf(obj)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	f := rv.FieldByName(field)
+	if !f.IsValid() || !f.CanInterface() {
+		return nil
+	}
+	return convert.FromGo(f)
 }
 
 // ============ Date/Time functions ============
 
 func nativeNow(args ...interface{}) (interface{}, error) {
-	return float64(time.Now().UnixMilli()), nil
+	return time.Now().UnixMilli(), nil
 }
 
 func nativeDate(args ...interface{}) (interface{}, error) {
@@ -1117,7 +1405,7 @@ func nativeDatetime(args ...interface{}) (interface{}, error) {
 
 func nativeTimestamp(args ...interface{}) (interface{}, error) {
 	if len(args) == 0 {
-		return float64(time.Now().UnixMilli()), nil
+		return time.Now().UnixMilli(), nil
 	}
 	dateStr, ok := args[0].(string)
 	if !ok {
@@ -1132,7 +1420,7 @@ func nativeTimestamp(args ...interface{}) (interface{}, error) {
 	}
 	for _, f := range formats {
 		if t, err := time.Parse(f, dateStr); err == nil {
-			return float64(t.UnixMilli()), nil
+			return t.UnixMilli(), nil
 		}
 	}
 	return nil, fmt.Errorf("cannot parse date: %s", dateStr)
@@ -1176,7 +1464,7 @@ func nativeYear(args ...interface{}) (interface{}, error) {
 		}
 		t = time.UnixMilli(int64(ts))
 	}
-	return float64(t.Year()), nil
+	return int64(t.Year()), nil
 }
 
 func nativeMonth(args ...interface{}) (interface{}, error) {
@@ -1188,7 +1476,7 @@ func nativeMonth(args ...interface{}) (interface{}, error) {
 		}
 		t = time.UnixMilli(int64(ts))
 	}
-	return float64(t.Month()), nil
+	return int64(t.Month()), nil
 }
 
 func nativeDay(args ...interface{}) (interface{}, error) {
@@ -1200,7 +1488,7 @@ func nativeDay(args ...interface{}) (interface{}, error) {
 		}
 		t = time.UnixMilli(int64(ts))
 	}
-	return float64(t.Day()), nil
+	return int64(t.Day()), nil
 }
 
 func nativeHour(args ...interface{}) (interface{}, error) {
@@ -1212,7 +1500,7 @@ func nativeHour(args ...interface{}) (interface{}, error) {
 		}
 		t = time.UnixMilli(int64(ts))
 	}
-	return float64(t.Hour()), nil
+	return int64(t.Hour()), nil
 }
 
 func nativeMinute(args ...interface{}) (interface{}, error) {
@@ -1224,7 +1512,7 @@ func nativeMinute(args ...interface{}) (interface{}, error) {
 		}
 		t = time.UnixMilli(int64(ts))
 	}
-	return float64(t.Minute()), nil
+	return int64(t.Minute()), nil
 }
 
 func nativeSecond(args ...interface{}) (interface{}, error) {
@@ -1236,7 +1524,7 @@ func nativeSecond(args ...interface{}) (interface{}, error) {
 		}
 		t = time.UnixMilli(int64(ts))
 	}
-	return float64(t.Second()), nil
+	return int64(t.Second()), nil
 }
 
 func nativeDayOfWeek(args ...interface{}) (interface{}, error) {
@@ -1248,7 +1536,7 @@ func nativeDayOfWeek(args ...interface{}) (interface{}, error) {
 		}
 		t = time.UnixMilli(int64(ts))
 	}
-	return float64(t.Weekday()), nil
+	return int64(t.Weekday()), nil
 }
 
 func nativeAddDays(args ...interface{}) (interface{}, error) {
@@ -1265,7 +1553,7 @@ func nativeAddDays(args ...interface{}) (interface{}, error) {
 	}
 	t := time.UnixMilli(int64(ts))
 	t = t.AddDate(0, 0, int(days))
-	return float64(t.UnixMilli()), nil
+	return t.UnixMilli(), nil
 }
 
 func nativeAddHours(args ...interface{}) (interface{}, error) {
@@ -1282,7 +1570,7 @@ func nativeAddHours(args ...interface{}) (interface{}, error) {
 	}
 	t := time.UnixMilli(int64(ts))
 	t = t.Add(time.Duration(hours) * time.Hour)
-	return float64(t.UnixMilli()), nil
+	return t.UnixMilli(), nil
 }
 
 func nativeDiffDays(args ...interface{}) (interface{}, error) {
@@ -1300,5 +1588,5 @@ func nativeDiffDays(args ...interface{}) (interface{}, error) {
 	t1 := time.UnixMilli(int64(ts1))
 	t2 := time.UnixMilli(int64(ts2))
 	diff := t2.Sub(t1)
-	return float64(int(diff.Hours() / 24)), nil
+	return int64(diff.Hours() / 24), nil
 }
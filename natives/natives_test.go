@@ -1,8 +1,13 @@
 package natives
 
 import (
+	"math"
+	"math/big"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/issadicko/kodi-script-go/decimal"
 )
 
 func TestStringFunctions(t *testing.T) {
@@ -15,6 +20,11 @@ func TestStringFunctions(t *testing.T) {
 		if err == nil {
 			t.Error("expected error for no args")
 		}
+		nested := map[string]interface{}{"prices": []interface{}{big.NewRat(3, 2), []big.Rat{*big.NewRat(1, 10)}}}
+		result, err = nativeToString(nested)
+		if err != nil || result != "map[prices:[1.5 [0.1]]]" {
+			t.Errorf("expected nested decimals in plain notation, got %v", result)
+		}
 	})
 
 	t.Run("toNumber", func(t *testing.T) {
@@ -42,7 +52,7 @@ func TestStringFunctions(t *testing.T) {
 
 	t.Run("length", func(t *testing.T) {
 		result, err := nativeLength("hello")
-		if err != nil || result != float64(5) {
+		if err != nil || result != int64(5) {
 			t.Errorf("expected 5, got %v", result)
 		}
 		_, err = nativeLength(42)
@@ -129,7 +139,7 @@ func TestStringFunctions(t *testing.T) {
 
 	t.Run("indexOf", func(t *testing.T) {
 		result, err := nativeIndexOf("hello world", "world")
-		if err != nil || result != float64(6) {
+		if err != nil || result != int64(6) {
 			t.Errorf("expected 6, got %v", result)
 		}
 	})
@@ -154,6 +164,45 @@ func TestStringFunctions(t *testing.T) {
 	})
 }
 
+func TestUnicodeStringFunctions(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       NativeFunc
+		args     []interface{}
+		expected interface{}
+	}{
+		{"length counts characters", nativeLength, []interface{}{"élève"}, int64(5)},
+		{"length of emoji counts UTF-16 units", nativeLength, []interface{}{"ok 👍"}, int64(5)},
+		{"length of decomposed accent", nativeLength, []interface{}{"e\u0301"}, int64(2)},
+		{"size of string", nativeSize, []interface{}{"façade"}, int64(6)},
+		{"size of emoji", nativeSize, []interface{}{"😀"}, int64(2)},
+		{"substring after emoji", nativeSubstring, []interface{}{"😀ab", int64(2)}, "ab"},
+		{"indexOf after emoji", nativeIndexOf, []interface{}{"😀ab", "b"}, int64(3)},
+		{"padLeft with emoji pad", nativePadLeft, []interface{}{"a", int64(5), "😀"}, "😀😀a"},
+		{"padRight never overshoots", nativePadRight, []interface{}{"a", int64(4), "😀"}, "a😀"},
+		{"substring from", nativeSubstring, []interface{}{"prénom", int64(3)}, "nom"},
+		{"substring range", nativeSubstring, []interface{}{"Zoë Zoé", int64(1), int64(3)}, "oë"},
+		{"substring inverted range", nativeSubstring, []interface{}{"été", int64(2), int64(1)}, ""},
+		{"indexOf after accents", nativeIndexOf, []interface{}{"à côté", "té"}, int64(4)},
+		{"indexOf missing", nativeIndexOf, []interface{}{"àé", "x"}, int64(-1)},
+		{"padLeft counts characters", nativePadLeft, []interface{}{"né", int64(4)}, "  né"},
+		{"padRight with accented pad", nativePadRight, []interface{}{"a", int64(3), "é"}, "aéé"},
+		{"padLeft uses first character", nativePadLeft, []interface{}{"7", int64(3), "·-"}, "··7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.fn(tt.args...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestMathFunctions(t *testing.T) {
 	t.Run("abs", func(t *testing.T) {
 		result, err := nativeAbs(float64(-5))
@@ -283,7 +332,7 @@ func TestRandomFunctions(t *testing.T) {
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
-		v := result.(float64)
+		v := result.(int64)
 		if v < 1 || v > 10 {
 			t.Errorf("expected [1,10], got %v", v)
 		}
@@ -460,6 +509,118 @@ func TestTypeFunctions(t *testing.T) {
 	})
 }
 
+func TestIntegerConversions(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       NativeFunc
+		args     []interface{}
+		expected interface{}
+	}{
+		{"int of float", nativeInt, []interface{}{float64(3.9)}, int64(3)},
+		{"int of int", nativeInt, []interface{}{int64(1<<62 + 1)}, int64(1<<62 + 1)},
+		{"int of string", nativeInt, []interface{}{" 9007199254740993 "}, int64(9007199254740993)},
+		{"int of decimal string", nativeInt, []interface{}{"-2.7"}, int64(-2)},
+		{"float of int", nativeFloat, []interface{}{int64(2)}, float64(2)},
+		{"float of string", nativeFloat, []interface{}{"0.25"}, 0.25},
+		{"abs keeps integers", nativeAbs, []interface{}{int64(-4)}, int64(4)},
+		{"min keeps integers", nativeMin, []interface{}{int64(4), int64(-1)}, int64(-1)},
+		{"max mixes to float", nativeMax, []interface{}{int64(4), float64(4.5)}, 4.5},
+		{"toNumber keeps integers", nativeToNumber, []interface{}{int64(7)}, int64(7)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.fn(tt.args...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %v (%T), got %v (%T)", tt.expected, tt.expected, result, result)
+			}
+		})
+	}
+
+	for _, arg := range []interface{}{"abc", true, math.NaN(), math.Inf(1)} {
+		if _, err := nativeInt(arg); err == nil {
+			t.Errorf("int(%v): expected error", arg)
+		}
+	}
+	if _, err := nativeFloat("abc"); err == nil {
+		t.Error("float(\"abc\"): expected error")
+	}
+}
+
+func TestRoundingModes(t *testing.T) {
+	// Each row rounds 2.5, -2.5, 2.45 (to 1 place) and 2.46 (to 1 place)
+	tests := []struct {
+		mode     string
+		expected [4]string
+	}{
+		{"half-up", [4]string{"3", "-3", "2.5", "2.5"}},
+		{"half-down", [4]string{"2", "-2", "2.4", "2.5"}},
+		{"half-even", [4]string{"2", "-2", "2.4", "2.5"}},
+		{"up", [4]string{"3", "-3", "2.5", "2.5"}},
+		{"down", [4]string{"2", "-2", "2.4", "2.4"}},
+		{"ceiling", [4]string{"3", "-2", "2.5", "2.5"}},
+		{"floor", [4]string{"2", "-3", "2.4", "2.4"}},
+	}
+
+	inputs := []struct {
+		value  string
+		places int64
+	}{{"2.5", 0}, {"-2.5", 0}, {"2.45", 1}, {"2.46", 1}}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			for i, in := range inputs {
+				d, _ := new(big.Rat).SetString(in.value)
+				result, err := nativeRound(d, in.places, tt.mode)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got := decimal.Format(result.(*big.Rat)); got != tt.expected[i] {
+					t.Errorf("round(%s, %d): expected %s, got %s", in.value, in.places, tt.expected[i], got)
+				}
+			}
+		})
+	}
+
+	t.Run("floats and integers", func(t *testing.T) {
+		cases := []struct {
+			args     []interface{}
+			expected interface{}
+		}{
+			{[]interface{}{2.675, int64(2)}, 2.68},
+			{[]interface{}{-2.5}, float64(-3)},
+			{[]interface{}{0.125, int64(2), "half-even"}, 0.12},
+			{[]interface{}{int64(7)}, int64(7)},
+			{[]interface{}{int64(1250), int64(-2)}, int64(1300)},
+			{[]interface{}{int64(1250), int64(-2), "half-even"}, int64(1200)},
+		}
+		for _, c := range cases {
+			result, err := nativeRound(c.args...)
+			if err != nil {
+				t.Fatalf("round%v: unexpected error: %v", c.args, err)
+			}
+			if result != c.expected {
+				t.Errorf("round%v: expected %v (%T), got %v (%T)", c.args, c.expected, c.expected, result, result)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := nativeRound(1.5, 1.5); err == nil {
+			t.Error("expected error for fractional places")
+		}
+		if _, err := nativeRound(1.5, int64(0), "nearest"); err == nil {
+			t.Error("expected error for unknown mode")
+		}
+		if _, err := nativeRound("1.5"); err == nil {
+			t.Error("expected error for string argument")
+		}
+	})
+}
+
 func TestArrayFunctions(t *testing.T) {
 	t.Run("sort", func(t *testing.T) {
 		arr := []interface{}{float64(3), float64(1), float64(2)}
@@ -512,15 +673,15 @@ func TestArrayFunctions(t *testing.T) {
 
 	t.Run("size", func(t *testing.T) {
 		result, err := nativeSize([]interface{}{1, 2, 3})
-		if err != nil || result != float64(3) {
+		if err != nil || result != int64(3) {
 			t.Errorf("expected 3, got %v", result)
 		}
 		result, err = nativeSize("hello")
-		if err != nil || result != float64(5) {
+		if err != nil || result != int64(5) {
 			t.Errorf("expected 5, got %v", result)
 		}
 		result, err = nativeSize(map[string]interface{}{"a": 1, "b": 2})
-		if err != nil || result != float64(2) {
+		if err != nil || result != int64(2) {
 			t.Errorf("expected 2, got %v", result)
 		}
 	})
@@ -577,6 +738,28 @@ func TestArrayFunctions(t *testing.T) {
 			t.Errorf("unexpected error: %v", err)
 		}
 	})
+
+	t.Run("go slices and maps", func(t *testing.T) {
+		result, err := nativeSort([]int{3, 1, 2})
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		sorted := result.([]interface{})
+		if sorted[0] != int64(1) || sorted[2] != int64(3) {
+			t.Errorf("sort failed: %v", sorted)
+		}
+		result, err = nativeFirst(&[2]string{"a", "b"})
+		if err != nil || result != "a" {
+			t.Errorf("expected a, got %v", result)
+		}
+		result, err = nativeSize(map[string]int{"a": 1, "b": 2})
+		if err != nil || result != int64(2) {
+			t.Errorf("expected 2, got %v", result)
+		}
+		if _, err := nativeReverse(42); err == nil {
+			t.Error("expected error for non-array argument")
+		}
+	})
 }
 
 func TestRegistry(t *testing.T) {
@@ -602,33 +785,145 @@ func TestRegistry(t *testing.T) {
 
 func TestCompareValues(t *testing.T) {
 	// nil comparisons
-	if compareValues(nil, nil) != 0 {
+	if compareValues(nil, nil, nil) != 0 {
 		t.Error("nil == nil should be 0")
 	}
-	if compareValues(nil, "a") >= 0 {
+	if compareValues(nil, "a", nil) >= 0 {
 		t.Error("nil < anything")
 	}
-	if compareValues("a", nil) <= 0 {
+	if compareValues("a", nil, nil) <= 0 {
 		t.Error("anything > nil")
 	}
 
 	// Number comparisons
-	if compareValues(float64(1), float64(2)) >= 0 {
+	if compareValues(float64(1), float64(2), nil) >= 0 {
 		t.Error("1 < 2")
 	}
-	if compareValues(float64(2), float64(1)) <= 0 {
+	if compareValues(float64(2), float64(1), nil) <= 0 {
 		t.Error("2 > 1")
 	}
-	if compareValues(float64(1), float64(1)) != 0 {
+	if compareValues(float64(1), float64(1), nil) != 0 {
 		t.Error("1 == 1")
 	}
 
 	// String comparisons
-	if compareValues("a", "b") >= 0 {
+	if compareValues("a", "b", nil) >= 0 {
 		t.Error("a < b")
 	}
 }
 
+// version implements the Compare protocol used by Compare.
+type version struct{ major, minor int }
+
+func (v version) Compare(other version) int {
+	if v.major != other.major {
+		return v.major - other.major
+	}
+	return v.minor - other.minor
+}
+
+func TestCompare(t *testing.T) {
+	jan := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	ignoreCase := func(a, b string) int {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	}
+
+	tests := []struct {
+		name     string
+		a, b     interface{}
+		collate  Collation
+		expected int
+	}{
+		{"integers", int64(1), int64(2), nil, -1},
+		{"host integers", int32(3), int64(2), nil, 1},
+		{"mixed numbers", int64(2), 2.0, nil, 0},
+		{"decimals", big.NewRat(1, 10), 0.1, nil, 0},
+		{"strings", "apple", "banana", nil, -1},
+		{"byte order", "Zebra", "apple", nil, -1},
+		{"collation", "Zebra", "apple", ignoreCase, 1},
+		{"booleans", true, false, nil, 1},
+		{"times", jan, feb, nil, -1},
+		{"time pointer", feb, &jan, nil, 1},
+		{"compare method", version{1, 10}, version{1, 9}, nil, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Compare(tt.a, tt.b, tt.collate)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, c)
+			}
+		})
+	}
+
+	for _, pair := range [][2]interface{}{{"a", int64(1)}, {nil, int64(1)}, {version{1, 0}, jan}, {true, "true"}} {
+		if _, err := Compare(pair[0], pair[1], nil); err == nil {
+			t.Errorf("Compare(%v, %v): expected error", pair[0], pair[1])
+		}
+	}
+}
+
+func TestOrderingNatives(t *testing.T) {
+	jan := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	result, err := nativeMin("pear", "apple", "fig")
+	if err != nil || result != "apple" {
+		t.Errorf("expected apple, got %v (%v)", result, err)
+	}
+	result, err = nativeMax(feb, jan)
+	if err != nil || result != feb {
+		t.Errorf("expected %v, got %v (%v)", feb, result, err)
+	}
+	result, err = nativeMin(int64(2), 2.5)
+	if err != nil || result != int64(2) {
+		t.Errorf("expected int64 2, got %v (%T)", result, result)
+	}
+	if _, err := nativeMax(int64(1), "a"); err == nil || !strings.Contains(err.Error(), "max: cannot compare") {
+		t.Errorf("expected comparison error, got %v", err)
+	}
+
+	result, err = nativeSort([]interface{}{true, false, true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sorted := result.([]interface{}); sorted[0] != false || sorted[2] != true {
+		t.Errorf("expected false first, got %v", sorted)
+	}
+
+	events := []interface{}{
+		map[string]interface{}{"at": feb},
+		map[string]interface{}{"at": jan},
+	}
+	result, err = nativeSortBy(events, "at")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first := result.([]interface{})[0].(map[string]interface{}); first["at"] != jan {
+		t.Errorf("expected the January event first, got %v", first["at"])
+	}
+
+	r := NewRegistry()
+	r.SetCollation(func(a, b string) int {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	})
+	result, err = r.Get("sort")([]interface{}{"b", "C", "a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sorted := result.([]interface{}); sorted[0] != "a" || sorted[1] != "b" || sorted[2] != "C" {
+		t.Errorf("expected [a b C], got %v", sorted)
+	}
+	result, err = r.Get("max")("b", "C", "a")
+	if err != nil || result != "C" {
+		t.Errorf("expected C, got %v (%v)", result, err)
+	}
+}
+
 func TestGetFieldValue(t *testing.T) {
 	obj := map[string]interface{}{"name": "test"}
 	if getFieldValue(obj, "name") != "test" {
@@ -640,6 +935,14 @@ func TestGetFieldValue(t *testing.T) {
 	if getFieldValue("not an object", "field") != nil {
 		t.Error("expected nil for non-object")
 	}
+
+	type item struct{ Price int }
+	if getFieldValue(&item{Price: 4}, "Price") != int64(4) {
+		t.Error("expected struct field converted to 4")
+	}
+	if getFieldValue(item{}, "Missing") != nil {
+		t.Error("expected nil for missing struct field")
+	}
 }
 
 func TestDateTimeFunctions(t *testing.T) {
@@ -648,7 +951,7 @@ func TestDateTimeFunctions(t *testing.T) {
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
-		ts := result.(float64)
+		ts := result.(int64)
 		if ts < 1700000000000 { // After Nov 2023
 			t.Errorf("timestamp too small: %v", ts)
 		}
@@ -693,7 +996,7 @@ func TestDateTimeFunctions(t *testing.T) {
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
-		ts := result.(float64)
+		ts := result.(int64)
 		if ts < 1700000000000 {
 			t.Errorf("timestamp too small: %v", ts)
 		}
@@ -730,7 +1033,7 @@ func TestDateTimeFunctions(t *testing.T) {
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
-		year := result.(float64)
+		year := result.(int64)
 		if year < 2024 {
 			t.Errorf("expected year >= 2024, got %v", year)
 		}
@@ -741,7 +1044,7 @@ func TestDateTimeFunctions(t *testing.T) {
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
-		month := result.(float64)
+		month := result.(int64)
 		if month < 1 || month > 12 {
 			t.Errorf("expected month 1-12, got %v", month)
 		}
@@ -752,7 +1055,7 @@ func TestDateTimeFunctions(t *testing.T) {
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
-		day := result.(float64)
+		day := result.(int64)
 		if day < 1 || day > 31 {
 			t.Errorf("expected day 1-31, got %v", day)
 		}
@@ -763,7 +1066,7 @@ func TestDateTimeFunctions(t *testing.T) {
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
-		hour := result.(float64)
+		hour := result.(int64)
 		if hour < 0 || hour > 23 {
 			t.Errorf("expected hour 0-23, got %v", hour)
 		}
@@ -774,7 +1077,7 @@ func TestDateTimeFunctions(t *testing.T) {
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
-		minute := result.(float64)
+		minute := result.(int64)
 		if minute < 0 || minute > 59 {
 			t.Errorf("expected minute 0-59, got %v", minute)
 		}
@@ -785,7 +1088,7 @@ func TestDateTimeFunctions(t *testing.T) {
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
-		second := result.(float64)
+		second := result.(int64)
 		if second < 0 || second > 59 {
 			t.Errorf("expected second 0-59, got %v", second)
 		}
@@ -796,7 +1099,7 @@ func TestDateTimeFunctions(t *testing.T) {
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
-		dow := result.(float64)
+		dow := result.(int64)
 		if dow < 0 || dow > 6 {
 			t.Errorf("expected dayOfWeek 0-6, got %v", dow)
 		}
@@ -804,14 +1107,14 @@ func TestDateTimeFunctions(t *testing.T) {
 
 	t.Run("addDays", func(t *testing.T) {
 		now, _ := nativeNow()
-		ts := now.(float64)
+		ts := now.(int64)
 		result, err := nativeAddDays(ts, float64(7))
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
-		newTs := result.(float64)
+		newTs := result.(int64)
 		diff := newTs - ts
-		expectedDiff := float64(7 * 24 * 60 * 60 * 1000) // 7 days in ms
+		expectedDiff := int64(7 * 24 * 60 * 60 * 1000) // 7 days in ms
 		if diff < expectedDiff-1000 || diff > expectedDiff+1000 {
 			t.Errorf("expected ~7 days diff, got %v", diff)
 		}
@@ -819,14 +1122,14 @@ func TestDateTimeFunctions(t *testing.T) {
 
 	t.Run("addHours", func(t *testing.T) {
 		now, _ := nativeNow()
-		ts := now.(float64)
+		ts := now.(int64)
 		result, err := nativeAddHours(ts, float64(24))
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
-		newTs := result.(float64)
+		newTs := result.(int64)
 		diff := newTs - ts
-		expectedDiff := float64(24 * 60 * 60 * 1000) // 24 hours in ms
+		expectedDiff := int64(24 * 60 * 60 * 1000) // 24 hours in ms
 		if diff < expectedDiff-1000 || diff > expectedDiff+1000 {
 			t.Errorf("expected ~24 hours diff, got %v", diff)
 		}
@@ -834,13 +1137,13 @@ func TestDateTimeFunctions(t *testing.T) {
 
 	t.Run("diffDays", func(t *testing.T) {
 		now, _ := nativeNow()
-		ts := now.(float64)
+		ts := now.(int64)
 		nextWeek, _ := nativeAddDays(ts, float64(7))
-		result, err := nativeDiffDays(ts, nextWeek.(float64))
+		result, err := nativeDiffDays(ts, nextWeek.(int64))
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
-		diff := result.(float64)
+		diff := result.(int64)
 		if diff != 7 {
 			t.Errorf("expected 7 days diff, got %v", diff)
 		}
@@ -28,7 +28,7 @@ func TestOperationLimit_SimpleScript(t *testing.T) {
 		t.Fatalf("Expected success, got errors: %v", result.Errors)
 	}
 
-	if result.Value != float64(3) {
+	if result.Value != int64(3) {
 		t.Errorf("Expected 3, got %v", result.Value)
 	}
 
@@ -138,7 +138,7 @@ func TestOperationLimit_NoLimitByDefault(t *testing.T) {
 		t.Fatalf("Default (no limit) should not cause errors: %v", result.Errors)
 	}
 
-	if result.Value != float64(55) {
+	if result.Value != int64(55) {
 		t.Errorf("Expected 55, got %v", result.Value)
 	}
 
@@ -164,7 +164,7 @@ func TestOperationLimit_ZeroMeansUnlimited(t *testing.T) {
 		t.Fatalf("Zero (unlimited) should not cause errors: %v", result.Errors)
 	}
 
-	if result.Value != float64(15) {
+	if result.Value != int64(15) {
 		t.Errorf("Expected 15, got %v", result.Value)
 	}
 
@@ -199,3 +199,58 @@ func TestOperationLimit_WithBindings(t *testing.T) {
 
 	t.Log("Operation limit works correctly with bound objects")
 }
+
+func TestOperationLimit_IncrementCounts(t *testing.T) {
+	// Each count++ is one statement: let + 3 updates + the final expression
+	script := `
+		let count = 0
+		count++
+		count++
+		--count
+		count
+	`
+
+	result := New(script).
+		WithMaxOperations(5).
+		SilentPrint(true).
+		Execute()
+	if len(result.Errors) > 0 {
+		t.Fatalf("Expected 5 operations to be enough, got errors: %v", result.Errors)
+	}
+	if result.Value != int64(1) {
+		t.Errorf("Expected 1, got %v", result.Value)
+	}
+
+	result = New(script).
+		WithMaxOperations(4).
+		SilentPrint(true).
+		Execute()
+	if len(result.Errors) == 0 {
+		t.Fatal("Expected max operations error with a limit of 4")
+	}
+	if result.Errors[0] != interpreter.ErrMaxOperationsExceeded.Error() {
+		t.Errorf("Expected %q, got %q", interpreter.ErrMaxOperationsExceeded.Error(), result.Errors[0])
+	}
+}
+
+func TestOperationLimit_HugeRange(t *testing.T) {
+	// Ranges are lazy: the limit is hit long before a billion values could be built
+	script := `
+		let sum = 0
+		for (n in 1..1000000000) {
+			sum += n
+		}
+	`
+
+	result := New(script).
+		WithMaxOperations(1000).
+		SilentPrint(true).
+		Execute()
+
+	if len(result.Errors) == 0 {
+		t.Fatal("Expected max operations error")
+	}
+	if result.Errors[0] != interpreter.ErrMaxOperationsExceeded.Error() {
+		t.Errorf("Expected %q, got %q", interpreter.ErrMaxOperationsExceeded.Error(), result.Errors[0])
+	}
+}
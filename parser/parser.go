@@ -3,7 +3,10 @@ package parser
 
 import (
 	"fmt"
+	"math/big"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/issadicko/kodi-script-go/ast"
 	"github.com/issadicko/kodi-script-go/lexer"
@@ -14,33 +17,50 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	TERNARY     // cond ? a : b
 	ELVIS       // ?:
 	OR          // ||
 	AND         // &&
+	BIT_OR      // |
+	BIT_XOR     // ^
+	BIT_AND     // &
 	EQUALS      // == !=
-	LESSGREATER // > < >= <=
+	LESSGREATER // > < >= <= in
+	RANGE       // .. ..<
+	SHIFT       // << >>
 	SUM         // + -
 	PRODUCT     // * /
-	PREFIX      // -X or !X
+	PREFIX      // -X or !X or ~X
+	POWER       // ** (binds tighter than a prefix on its left: -2 ** 2 is -4)
 	CALL        // func(x)
 	ACCESS      // . ?.
 )
 
 var precedences = map[token.Type]int{
+	token.QUESTION:    TERNARY,
 	token.ELVIS:       ELVIS,
 	token.OR:          OR,
 	token.AND:         AND,
+	token.BIT_OR:      BIT_OR,
+	token.BIT_XOR:     BIT_XOR,
+	token.BIT_AND:     BIT_AND,
 	token.EQ:          EQUALS,
 	token.NOT_EQ:      EQUALS,
 	token.LT:          LESSGREATER,
 	token.GT:          LESSGREATER,
 	token.LT_EQ:       LESSGREATER,
 	token.GT_EQ:       LESSGREATER,
+	token.IN:          LESSGREATER,
+	token.DOT_DOT:     RANGE,
+	token.DOT_DOT_LT:  RANGE,
+	token.SHIFT_LEFT:  SHIFT,
+	token.SHIFT_RIGHT: SHIFT,
 	token.PLUS:        SUM,
 	token.MINUS:       SUM,
 	token.ASTERISK:    PRODUCT,
 	token.PERCENT:     PRODUCT,
 	token.SLASH:       PRODUCT,
+	token.POWER:       POWER,
 	token.LPAREN:      CALL,
 	token.LBRACKET:    ACCESS,
 	token.DOT:         ACCESS,
@@ -56,8 +76,14 @@ type Parser struct {
 
 	prefixParseFns map[token.Type]prefixParseFn
 	infixParseFns  map[token.Type]infixParseFn
+
+	scopes []scope // declared names, innermost last
 }
 
+// scope records the names declared in a block or parameter list;
+// the value is true for constants.
+type scope map[string]bool
+
 type (
 	prefixParseFn func() ast.Expression
 	infixParseFn  func(ast.Expression) ast.Expression
@@ -65,7 +91,7 @@ type (
 
 // New creates a new Parser.
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: []string{}}
+	p := &Parser{l: l, errors: []string{}, scopes: []scope{{}}}
 
 	p.prefixParseFns = make(map[token.Type]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
@@ -77,10 +103,13 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.NULL, p.parseNullLiteral)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.NOT, p.parsePrefixExpression)
+	p.registerPrefix(token.BIT_NOT, p.parsePrefixExpression)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseObjectLiteral)
 	p.registerPrefix(token.FN, p.parseFunctionLiteral)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)
 
 	p.infixParseFns = make(map[token.Type]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
@@ -94,9 +123,19 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.GT, p.parseInfixExpression)
 	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.IN, p.parseInfixExpression)
 	p.registerInfix(token.AND, p.parseInfixExpression)
 	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_AND, p.parseInfixExpression)
+	p.registerInfix(token.BIT_OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_XOR, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_LEFT, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_RIGHT, p.parseInfixExpression)
+	p.registerInfix(token.POWER, p.parseInfixExpression)
 	p.registerInfix(token.ELVIS, p.parseElvisExpression)
+	p.registerInfix(token.QUESTION, p.parseConditionalExpression)
+	p.registerInfix(token.DOT_DOT, p.parseRangeExpression)
+	p.registerInfix(token.DOT_DOT_LT, p.parseRangeExpression)
 	p.registerInfix(token.DOT, p.parsePropertyAccess)
 	p.registerInfix(token.SAFE_ACCESS, p.parseSafeAccess)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
@@ -123,8 +162,62 @@ func (p *Parser) Errors() []string {
 }
 
 func (p *Parser) addError(format string, args ...interface{}) {
+	p.addErrorAt(p.curToken, format, args...)
+}
+
+func (p *Parser) addErrorAt(tok token.Token, format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
-	p.errors = append(p.errors, fmt.Sprintf("line %d, col %d: %s", p.curToken.Line, p.curToken.Column, msg))
+	p.errors = append(p.errors, fmt.Sprintf("line %d, col %d: %s", tok.Line, tok.Column, msg))
+}
+
+func (p *Parser) pushScope() {
+	p.scopes = append(p.scopes, scope{})
+}
+
+func (p *Parser) popScope() {
+	p.scopes = p.scopes[:len(p.scopes)-1]
+}
+
+// declare records a name in the innermost scope. A constant cannot be
+// redeclared in the same scope, but can be shadowed in a nested one.
+func (p *Parser) declare(ident *ast.Identifier, constant bool) {
+	current := p.scopes[len(p.scopes)-1]
+	if wasConst, ok := current[ident.Value]; ok {
+		if wasConst {
+			p.addErrorAt(ident.Token, "cannot redeclare constant '%s'", ident.Value)
+		} else if constant {
+			p.addErrorAt(ident.Token, "cannot declare constant '%s': name already declared in this scope", ident.Value)
+		}
+	}
+	current[ident.Value] = constant
+}
+
+// declarePattern declares every name bound by an identifier or destructuring pattern.
+func (p *Parser) declarePattern(target ast.Expression, constant bool) {
+	switch t := target.(type) {
+	case *ast.Identifier:
+		p.declare(t, constant)
+	case *ast.ObjectPattern:
+		for _, prop := range t.Properties {
+			p.declarePattern(prop.Target, constant)
+		}
+	case *ast.ArrayPattern:
+		for _, elem := range t.Elements {
+			p.declarePattern(elem.Target, constant)
+		}
+	}
+}
+
+// checkAssignable reports an assignment to a name whose nearest declaration is a constant.
+func (p *Parser) checkAssignable(ident *ast.Identifier) {
+	for idx := len(p.scopes) - 1; idx >= 0; idx-- {
+		if constant, ok := p.scopes[idx][ident.Value]; ok {
+			if constant {
+				p.addErrorAt(ident.Token, "cannot assign to constant '%s'", ident.Value)
+			}
+			return
+		}
+	}
 }
 
 func (p *Parser) nextToken() {
@@ -197,7 +290,7 @@ func (p *Parser) ParseProgram() *ast.Program {
 
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
-	case token.LET:
+	case token.LET, token.CONST:
 		return p.parseVarDecl()
 	case token.IF:
 		return p.parseIfStatement()
@@ -207,10 +300,32 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseForStatement()
 	case token.WHILE:
 		return p.parseWhileStatement()
+	case token.BREAK:
+		return p.parseBreakStatement()
+	case token.CONTINUE:
+		return p.parseContinueStatement()
+	case token.TRY:
+		return p.parseTryStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.EXPORT:
+		return p.parseExportStatement()
+	case token.INCREMENT, token.DECREMENT:
+		return p.parsePrefixUpdate()
+	case token.FN:
+		if p.peekTokenIs(token.IDENT) {
+			return p.parseFunctionDecl()
+		}
+		return p.parseExpressionStatement()
 	case token.IDENT:
-		if p.peekTokenIs(token.ASSIGN) {
+		if p.peekToken.Type.IsAssignment() {
 			return p.parseAssignment()
 		}
+		if p.peekTokenIs(token.COLON) {
+			return p.parseLabeledStatement()
+		}
 		return p.parseExpressionStatement()
 	default:
 		return p.parseExpressionStatement()
@@ -218,14 +333,21 @@ func (p *Parser) parseStatement() ast.Statement {
 }
 
 func (p *Parser) parseVarDecl() *ast.VarDecl {
-	stmt := &ast.VarDecl{Token: p.curToken}
+	stmt := &ast.VarDecl{Token: p.curToken, Const: p.curTokenIs(token.CONST), Doc: p.curToken.Doc}
 
-	if !p.expectPeek(token.IDENT) {
-		return nil
+	if p.peekTokenIs(token.LBRACE) || p.peekTokenIs(token.LBRACKET) {
+		p.nextToken()
+		stmt.Pattern = p.parseBindingTarget()
+		if stmt.Pattern == nil {
+			return nil
+		}
+	} else {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	}
 
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
@@ -233,9 +355,104 @@ func (p *Parser) parseVarDecl() *ast.VarDecl {
 	p.nextToken()
 	stmt.Value = p.parseExpression(LOWEST)
 
+	// Declared after the value, so const x = x + 1 refers to an outer x
+	if stmt.Pattern != nil {
+		p.declarePattern(stmt.Pattern, stmt.Const)
+	} else {
+		p.declare(stmt.Name, stmt.Const)
+	}
+
 	return stmt
 }
 
+// parseBindingTarget parses a name to bind: an identifier, an object pattern
+// {a, b: alias = default, c: {d}} or an array pattern [a, b = default, [c]].
+func (p *Parser) parseBindingTarget() ast.Expression {
+	switch p.curToken.Type {
+	case token.IDENT:
+		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	case token.LBRACE:
+		return p.parseObjectPattern()
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	default:
+		p.addError("expected identifier or destructuring pattern, got %s", p.curToken.Type)
+		return nil
+	}
+}
+
+// parseObjectPattern parses: {name, key: target, key = default}
+func (p *Parser) parseObjectPattern() ast.Expression {
+	pattern := &ast.ObjectPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		if !p.curTokenIs(token.IDENT) && !p.curTokenIs(token.STRING) {
+			p.addError("expected property name in object pattern, got %s", p.curToken.Type)
+			return nil
+		}
+		prop := &ast.PatternProperty{Key: p.curToken.Literal}
+
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			p.nextToken()
+			prop.Target = p.parseBindingTarget()
+			if prop.Target == nil {
+				return nil
+			}
+		} else if p.curTokenIs(token.IDENT) {
+			prop.Target = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		} else {
+			p.addError("quoted key %q requires a target name", prop.Key)
+			return nil
+		}
+
+		prop.Default = p.parsePatternDefault()
+		pattern.Properties = append(pattern.Properties, prop)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken() // consume '}'
+
+	return pattern
+}
+
+// parseArrayPattern parses: [a, b = default, [c, d]]
+func (p *Parser) parseArrayPattern() ast.Expression {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+
+		elem := &ast.PatternElement{Target: p.parseBindingTarget()}
+		if elem.Target == nil {
+			return nil
+		}
+		elem.Default = p.parsePatternDefault()
+		pattern.Elements = append(pattern.Elements, elem)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken() // consume ']'
+
+	return pattern
+}
+
+// parsePatternDefault parses an optional "= expr" default in a destructuring pattern.
+func (p *Parser) parsePatternDefault() ast.Expression {
+	if !p.peekTokenIs(token.ASSIGN) {
+		return nil
+	}
+	p.nextToken()
+	p.nextToken()
+	return p.parseExpression(LOWEST)
+}
+
 // parseReturnStatement parses: return [expr]
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
@@ -261,11 +478,38 @@ func (p *Parser) parseForStatement() *ast.ForStatement {
 		return nil
 	}
 
-	// Expect identifier (loop variable)
-	if !p.expectPeek(token.IDENT) {
+	// Expect identifier or destructuring pattern (loop variable)
+	p.pushScope()
+	defer p.popScope()
+	p.nextToken()
+	target := p.parseBindingTarget()
+	if target == nil {
 		return nil
 	}
-	stmt.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	p.declarePattern(target, false)
+
+	// Two-variable form: for (index, item in arr) or for (key, value in obj)
+	if p.peekTokenIs(token.COMMA) {
+		index, ok := target.(*ast.Identifier)
+		if !ok {
+			p.addError("the index variable of a for loop must be an identifier")
+			return nil
+		}
+		stmt.Index = index
+		p.nextToken()
+		p.nextToken()
+		target = p.parseBindingTarget()
+		if target == nil {
+			return nil
+		}
+		p.declarePattern(target, false)
+	}
+
+	if ident, ok := target.(*ast.Identifier); ok {
+		stmt.Variable = ident
+	} else {
+		stmt.Pattern = target
+	}
 
 	// Expect 'in'
 	if !p.expectPeek(token.IN) {
@@ -321,11 +565,212 @@ func (p *Parser) parseWhileStatement() *ast.WhileStatement {
 	return stmt
 }
 
+// parseBreakStatement parses: break [label]
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+	stmt := &ast.BreakStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		stmt.Label = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	return stmt
+}
+
+// parseContinueStatement parses: continue [label]
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	stmt := &ast.ContinueStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		stmt.Label = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	return stmt
+}
+
+// parseTryStatement parses: try { } catch (e) { } finally { }
+// The catch variable is optional, and either catch or finally may be omitted.
+func (p *Parser) parseTryStatement() *ast.TryStatement {
+	stmt := &ast.TryStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+		p.pushScope()
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				p.popScope()
+				return nil
+			}
+			stmt.Param = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			p.declare(stmt.Param, false)
+			if !p.expectPeek(token.RPAREN) {
+				p.popScope()
+				return nil
+			}
+		}
+		if !p.expectPeek(token.LBRACE) {
+			p.popScope()
+			return nil
+		}
+		stmt.Handler = p.parseBlockStatement()
+		p.popScope()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.Finalizer = p.parseBlockStatement()
+	}
+
+	if stmt.Handler == nil && stmt.Finalizer == nil {
+		p.addError("try requires a catch or finally block")
+		return nil
+	}
+
+	return stmt
+}
+
+// parseThrowStatement parses: throw expr
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+
+	return stmt
+}
+
+// parseImportStatement parses: import { name, other as alias } from "module"
+// Imported names are bound as constants.
+func (p *Parser) parseImportStatement() ast.Statement {
+	stmt := &ast.ImportStatement{Token: p.curToken}
+
+	if len(p.scopes) > 1 {
+		p.addError("import is only allowed at the top level")
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		spec := &ast.ImportSpecifier{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+
+		if p.peekTokenIs(token.IDENT) && p.peekToken.Literal == "as" {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			spec.Alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		}
+		stmt.Specifiers = append(stmt.Specifiers, spec)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken() // consume '}'
+
+	if !p.peekTokenIs(token.IDENT) || p.peekToken.Literal != "from" {
+		p.addError("expected 'from' after import list, got %s", p.peekToken.Type)
+		return nil
+	}
+	p.nextToken()
+
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+	stmt.Source = p.curToken.Literal
+
+	for _, spec := range stmt.Specifiers {
+		p.declare(spec.LocalName(), true)
+	}
+
+	return stmt
+}
+
+// parseExportStatement parses: export let ..., export const ... or export fn name() { }
+func (p *Parser) parseExportStatement() ast.Statement {
+	stmt := &ast.ExportStatement{Token: p.curToken}
+
+	if len(p.scopes) > 1 {
+		p.addError("export is only allowed at the top level")
+		return nil
+	}
+
+	p.nextToken()
+	switch {
+	case p.curTokenIs(token.LET), p.curTokenIs(token.CONST):
+		decl := p.parseVarDecl()
+		if decl == nil {
+			return nil
+		}
+		// The doc comment sits above the export keyword
+		decl.Doc = stmt.Token.Doc
+		stmt.Declaration = decl
+	case p.curTokenIs(token.FN) && p.peekTokenIs(token.IDENT):
+		decl := p.parseFunctionDecl()
+		if decl == nil {
+			return nil
+		}
+		decl.(*ast.FunctionDecl).Doc = stmt.Token.Doc
+		stmt.Declaration = decl
+	default:
+		p.addError("export must be followed by let, const or a named fn declaration")
+		return nil
+	}
+
+	return stmt
+}
+
+// parseLabeledStatement parses: label: for (...) { } or label: while (...) { }
+func (p *Parser) parseLabeledStatement() ast.Statement {
+	label := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	p.nextToken() // consume label, curToken is ':'
+	p.nextToken() // move to the loop keyword
+
+	switch p.curToken.Type {
+	case token.FOR:
+		stmt := p.parseForStatement()
+		if stmt == nil {
+			return nil
+		}
+		stmt.Label = label
+		return stmt
+	case token.WHILE:
+		stmt := p.parseWhileStatement()
+		if stmt == nil {
+			return nil
+		}
+		stmt.Label = label
+		return stmt
+	default:
+		p.addError("label '%s' must be followed by a for or while loop", label.Value)
+		return nil
+	}
+}
+
 func (p *Parser) parseAssignment() *ast.Assignment {
 	stmt := &ast.Assignment{Token: p.curToken}
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	p.checkAssignable(stmt.Name)
 
-	p.nextToken() // consume ASSIGN
+	p.nextToken() // consume ASSIGN (or compound operator)
+	stmt.Operator = p.curToken.Literal
 	p.nextToken() // move to expression
 
 	stmt.Value = p.parseExpression(LOWEST)
@@ -356,6 +801,19 @@ func (p *Parser) parseIfStatement() *ast.IfStatement {
 	if p.peekTokenIs(token.ELSE) {
 		p.nextToken()
 
+		// else if (...) { } is stored as an alternative block holding the nested if
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			block := &ast.BlockStatement{Token: p.curToken}
+			nested := p.parseIfStatement()
+			if nested == nil {
+				return nil
+			}
+			block.Statements = []ast.Statement{nested}
+			stmt.Alternative = block
+			return stmt
+		}
+
 		if !p.expectPeek(token.LBRACE) {
 			return nil
 		}
@@ -370,6 +828,9 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
 
+	p.pushScope()
+	defer p.popScope()
+
 	p.nextToken() // consume the opening brace
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
@@ -390,9 +851,82 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	return block
 }
 
-func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
+func (p *Parser) parseExpressionStatement() ast.Statement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
 	stmt.Expression = p.parseExpression(LOWEST)
+
+	if p.peekToken.Type.IsAssignment() {
+		return p.parseMemberAssignment(stmt.Expression)
+	}
+
+	if p.peekTokenIs(token.INCREMENT) || p.peekTokenIs(token.DECREMENT) {
+		p.nextToken()
+		return p.parseUpdate(stmt.Expression, p.curToken)
+	}
+
+	return stmt
+}
+
+// parsePrefixUpdate parses: ++target or --target
+func (p *Parser) parsePrefixUpdate() ast.Statement {
+	opToken := p.curToken
+	p.nextToken()
+	target := p.parseExpression(PREFIX)
+	return p.parseUpdate(target, opToken)
+}
+
+// parseUpdate builds the statement for target++ / target-- as target += 1 / target -= 1.
+func (p *Parser) parseUpdate(target ast.Expression, opToken token.Token) ast.Statement {
+	operator := "+="
+	if opToken.Type == token.DECREMENT {
+		operator = "-="
+	}
+	one := &ast.IntegerLiteral{Token: opToken, Value: 1}
+
+	switch t := target.(type) {
+	case *ast.Identifier:
+		p.checkAssignable(t)
+		return &ast.Assignment{Token: t.Token, Name: t, Operator: operator, Value: one}
+	case *ast.PropertyAccessExpr, *ast.IndexExpr:
+		if isOptionalIndex(target) {
+			p.addError("invalid %s target", opToken.Literal)
+			return nil
+		}
+		return &ast.MemberAssignment{Token: opToken, Target: target, Operator: operator, Value: one}
+	default:
+		p.addError("invalid %s target", opToken.Literal)
+		return nil
+	}
+}
+
+// isOptionalIndex reports whether target is a null-safe index (arr?.[0]),
+// which cannot be assigned to.
+func isOptionalIndex(target ast.Expression) bool {
+	index, ok := target.(*ast.IndexExpr)
+	return ok && index.Optional
+}
+
+// parseMemberAssignment parses: target = expr where target is a property or index chain.
+func (p *Parser) parseMemberAssignment(target ast.Expression) ast.Statement {
+	switch target.(type) {
+	case *ast.PropertyAccessExpr, *ast.IndexExpr:
+	default:
+		p.nextToken()
+		p.addError("invalid assignment target")
+		return nil
+	}
+	if isOptionalIndex(target) {
+		p.nextToken()
+		p.addError("invalid assignment target")
+		return nil
+	}
+
+	p.nextToken() // move to ASSIGN (or compound operator)
+	stmt := &ast.MemberAssignment{Token: p.curToken, Target: target, Operator: p.curToken.Literal}
+
+	p.nextToken() // move to expression
+	stmt.Value = p.parseExpression(LOWEST)
+
 	return stmt
 }
 
@@ -420,15 +954,65 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 }
 
 func (p *Parser) parseIdentifier() ast.Expression {
-	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	// Single-parameter lambda: x => expr
+	if p.peekTokenIs(token.FAT_ARROW) {
+		p.nextToken()
+		lit := &ast.FunctionLiteral{
+			Token:      p.curToken,
+			Parameters: []*ast.Identifier{ident},
+			Defaults:   []ast.Expression{nil},
+		}
+		p.pushScope()
+		p.declare(ident, false)
+		ok := p.parseLambdaBody(lit)
+		p.popScope()
+		if !ok {
+			return nil
+		}
+		return lit
+	}
+
+	return ident
 }
 
+// parseNumberLiteral parses the literal forms read by the lexer: 42, 4.2,
+// 1e-3, 0xFF, 0o17, 0b1010, 1_000_000 and the decimal 19.99d. Decimal
+// integers that do not fit in an int64 become floats.
 func (p *Parser) parseNumberLiteral() ast.Expression {
+	literal := p.curToken.Literal
+	if len(literal) > 1 && literal[0] == '0' && strings.ContainsRune("xXoObB", rune(literal[1])) {
+		return p.parseRadixLiteral(literal)
+	}
+
+	digits, isDecimal := strings.CutSuffix(literal, "d")
+	if problem := malformedNumber(digits); problem != "" {
+		p.addError("malformed number %s: %s", literal, problem)
+		return nil
+	}
+	digits = strings.ReplaceAll(digits, "_", "")
+
+	if isDecimal {
+		value, ok := new(big.Rat).SetString(digits)
+		if !ok {
+			p.addError("could not parse %q as decimal", literal)
+			return nil
+		}
+		return &ast.DecimalLiteral{Token: p.curToken, Value: value}
+	}
+
+	if !strings.ContainsAny(digits, ".eE") {
+		if value, err := strconv.ParseInt(digits, 10, 64); err == nil {
+			return &ast.IntegerLiteral{Token: p.curToken, Value: value}
+		}
+	}
+
 	lit := &ast.NumberLiteral{Token: p.curToken}
 
-	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	value, err := strconv.ParseFloat(digits, 64)
 	if err != nil {
-		p.addError("could not parse %q as number", p.curToken.Literal)
+		p.addError("number %s is out of range", literal)
 		return nil
 	}
 
@@ -436,6 +1020,104 @@ func (p *Parser) parseNumberLiteral() ast.Expression {
 	return lit
 }
 
+// parseRadixLiteral parses an integer written in hexadecimal (0xFF), octal
+// (0o17) or binary (0b1010). These spell out bit patterns, so a value that
+// does not fit in an int64 is an error rather than a rounded float.
+func (p *Parser) parseRadixLiteral(literal string) ast.Expression {
+	base, name := 16, "hexadecimal"
+	switch literal[1] {
+	case 'o', 'O':
+		base, name = 8, "octal"
+	case 'b', 'B':
+		base, name = 2, "binary"
+	}
+
+	digits := literal[2:]
+	if digits == "" {
+		p.addError("malformed number %s: %s literal has no digits", literal, name)
+		return nil
+	}
+	for _, ch := range digits {
+		if ch != '_' && !isDigitInBase(ch, base) {
+			p.addError("malformed number %s: invalid digit %q in %s literal", literal, ch, name)
+			return nil
+		}
+	}
+	if problem := misplacedSeparator(digits); problem != "" {
+		p.addError("malformed number %s: %s", literal, problem)
+		return nil
+	}
+
+	value, _ := new(big.Int).SetString(strings.ReplaceAll(digits, "_", ""), base)
+	if !value.IsInt64() {
+		p.addError("number %s is out of range: %s literals must fit in a 64-bit signed integer", literal, name)
+		return nil
+	}
+	return &ast.IntegerLiteral{Token: p.curToken, Value: value.Int64()}
+}
+
+// malformedNumber describes what is wrong with a decimal number literal
+// without its d suffix, or returns "" when it is well formed.
+func malformedNumber(s string) string {
+	i := 0
+	// digits reads a run of digits and underscores
+	digits := func() string {
+		start := i
+		for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '_') {
+			i++
+		}
+		return s[start:i]
+	}
+
+	if problem := misplacedSeparator(digits()); problem != "" {
+		return problem
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		if problem := misplacedSeparator(digits()); problem != "" {
+			return problem
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		exponent := digits()
+		if exponent == "" {
+			return "exponent has no digits"
+		}
+		if problem := misplacedSeparator(exponent); problem != "" {
+			return problem
+		}
+	}
+	if i < len(s) {
+		ch, _ := utf8.DecodeRuneInString(s[i:])
+		return fmt.Sprintf("unexpected character %q", ch)
+	}
+	return ""
+}
+
+// misplacedSeparator reports an underscore that does not sit between two
+// digits of a run, as in 1__000, 100_ or 1_.5.
+func misplacedSeparator(run string) string {
+	if strings.HasPrefix(run, "_") || strings.HasSuffix(run, "_") || strings.Contains(run, "__") {
+		return "'_' must separate digits"
+	}
+	return ""
+}
+
+// isDigitInBase reports whether ch is a digit in the given base (2, 8 or 16).
+func isDigitInBase(ch rune, base int) bool {
+	switch {
+	case ch >= '0' && ch <= '9':
+		return int(ch-'0') < base
+	case ch >= 'a' && ch <= 'f', ch >= 'A' && ch <= 'F':
+		return base == 16
+	}
+	return false
+}
+
 func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
@@ -508,6 +1190,18 @@ func (p *Parser) parseStringTemplate() ast.Expression {
 	return template
 }
 
+// parseIllegal reports a token the lexer rejected: its literal is either the
+// offending character or the error found in a string, such as
+// "unterminated string".
+func (p *Parser) parseIllegal() ast.Expression {
+	if utf8.RuneCountInString(p.curToken.Literal) > 1 {
+		p.addError("%s", p.curToken.Literal)
+	} else {
+		p.addError("unexpected character %q", p.curToken.Literal)
+	}
+	return nil
+}
+
 func (p *Parser) parseBooleanLiteral() ast.Expression {
 	return &ast.BooleanLiteral{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
@@ -529,6 +1223,10 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 }
 
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	if p.parenStartsLambda() {
+		return p.parseLambda()
+	}
+
 	p.nextToken()
 
 	exp := p.parseExpression(LOWEST)
@@ -540,6 +1238,76 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 	return exp
 }
 
+// parenStartsLambda reports whether the '(' at curToken opens a lambda
+// parameter list. It looks at most three tokens ahead, so nested
+// parentheses are not rescanned.
+func (p *Parser) parenStartsLambda() bool {
+	// Assignments and commas cannot appear in a parenthesized expression, so
+	// at most two tokens past the first parameter decide: "(a," and "(a ="
+	// only start parameter lists, "(a)" and "()" need a following "=>".
+	switch p.peekToken.Type {
+	case token.ELLIPSIS:
+		return true
+	case token.RPAREN:
+		return p.l.Clone().NextToken().Type == token.FAT_ARROW
+	case token.IDENT:
+	default:
+		return false
+	}
+
+	l := p.l.Clone()
+	switch l.NextToken().Type {
+	case token.COMMA, token.ASSIGN:
+		return true
+	case token.RPAREN:
+		return l.NextToken().Type == token.FAT_ARROW
+	}
+	return false
+}
+
+// parseLambda parses: (a, b = 1, ...rest) => expr or (a, b) => { ... }
+func (p *Parser) parseLambda() ast.Expression {
+	lit := &ast.FunctionLiteral{}
+
+	p.pushScope()
+	defer p.popScope()
+
+	if !p.parseFunctionParameters(lit) {
+		return nil
+	}
+	if !p.expectPeek(token.FAT_ARROW) {
+		return nil
+	}
+	lit.Token = p.curToken
+
+	if !p.parseLambdaBody(lit) {
+		return nil
+	}
+	return lit
+}
+
+// parseLambdaBody parses what follows '=>': a block, or a single expression
+// that becomes an implicit return.
+func (p *Parser) parseLambdaBody(lit *ast.FunctionLiteral) bool {
+	if p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		lit.Body = p.parseBlockStatement()
+		return true
+	}
+
+	arrow := p.curToken
+	p.nextToken()
+	value := p.parseExpression(LOWEST)
+	if value == nil {
+		return false
+	}
+	lit.Body = &ast.BlockStatement{
+		Token:      arrow,
+		Statements: []ast.Statement{&ast.ReturnStatement{Token: arrow, Value: value}},
+	}
+	return true
+}
+
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 
@@ -557,12 +1325,12 @@ func (p *Parser) parseExpressionList(end token.Type) []ast.Expression {
 	}
 
 	p.nextToken()
-	list = append(list, p.parseExpression(LOWEST))
+	list = append(list, p.parseListElement())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		list = append(list, p.parseExpression(LOWEST))
+		list = append(list, p.parseListElement())
 	}
 
 	if !p.expectPeek(end) {
@@ -572,9 +1340,21 @@ func (p *Parser) parseExpressionList(end token.Type) []ast.Expression {
 	return list
 }
 
+// parseListElement parses one element of an array literal or argument list,
+// which may be a spread: ...expr
+func (p *Parser) parseListElement() ast.Expression {
+	if !p.curTokenIs(token.ELLIPSIS) {
+		return p.parseExpression(LOWEST)
+	}
+
+	spread := &ast.SpreadExpr{Token: p.curToken}
+	p.nextToken()
+	spread.Value = p.parseExpression(LOWEST)
+	return spread
+}
+
 func (p *Parser) parseObjectLiteral() ast.Expression {
 	object := &ast.ObjectLiteral{Token: p.curToken}
-	object.Pairs = make(map[string]ast.Expression)
 
 	if p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
@@ -584,6 +1364,14 @@ func (p *Parser) parseObjectLiteral() ast.Expression {
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
 
+		if p.curTokenIs(token.ELLIPSIS) {
+			object.Entries = append(object.Entries, ast.ObjectEntry{Value: p.parseListElement()})
+			if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+				return nil
+			}
+			continue
+		}
+
 		// Support both string "key" and identifier key
 		var key string
 		if p.curTokenIs(token.STRING) || p.curTokenIs(token.IDENT) {
@@ -600,7 +1388,7 @@ func (p *Parser) parseObjectLiteral() ast.Expression {
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
 
-		object.Pairs[key] = value
+		object.Entries = append(object.Entries, ast.ObjectEntry{Key: key, Value: value})
 
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
@@ -635,6 +1423,9 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := p.curPrecedence()
+	if p.curTokenIs(token.POWER) {
+		precedence-- // right-associative: 2 ** 3 ** 2 is 2 ** (3 ** 2)
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
 
@@ -653,6 +1444,27 @@ func (p *Parser) parseElvisExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+// parseConditionalExpression parses: condition ? consequence : alternative
+// The operator is right-associative: a ? b : c ? d : e groups as a ? b : (c ? d : e).
+func (p *Parser) parseConditionalExpression(condition ast.Expression) ast.Expression {
+	expression := &ast.ConditionalExpr{
+		Token:     p.curToken,
+		Condition: condition,
+	}
+
+	p.nextToken()
+	expression.Consequence = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Alternative = p.parseExpression(LOWEST)
+
+	return expression
+}
+
 func (p *Parser) parsePropertyAccess(left ast.Expression) ast.Expression {
 	expression := &ast.PropertyAccessExpr{
 		Token:  p.curToken,
@@ -668,7 +1480,24 @@ func (p *Parser) parsePropertyAccess(left ast.Expression) ast.Expression {
 	return expression
 }
 
+// parseSafeAccess parses the null-safe forms obj?.property, obj?.[index] and fn?.(args).
 func (p *Parser) parseSafeAccess(left ast.Expression) ast.Expression {
+	switch {
+	case p.peekTokenIs(token.LBRACKET):
+		p.nextToken()
+		exp, ok := p.parseIndexExpression(left).(*ast.IndexExpr)
+		if !ok {
+			return nil
+		}
+		exp.Optional = true
+		return exp
+	case p.peekTokenIs(token.LPAREN):
+		p.nextToken()
+		exp := &ast.CallExpr{Token: p.curToken, Function: left, Optional: true}
+		exp.Arguments = p.parseCallArguments()
+		return exp
+	}
+
 	expression := &ast.SafeAccessExpr{
 		Token:  p.curToken,
 		Object: left,
@@ -686,46 +1515,97 @@ func (p *Parser) parseSafeAccess(left ast.Expression) ast.Expression {
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	lit := &ast.FunctionLiteral{Token: p.curToken}
 
-	if !p.expectPeek(token.LPAREN) {
+	if !p.parseFunctionSignature(lit) {
 		return nil
 	}
 
-	lit.Parameters = p.parseFunctionParameters()
+	return lit
+}
+
+// parseFunctionDecl parses: fn name(params) { body }
+func (p *Parser) parseFunctionDecl() ast.Statement {
+	stmt := &ast.FunctionDecl{Token: p.curToken, Doc: p.curToken.Doc}
 
-	if !p.expectPeek(token.LBRACE) {
+	p.nextToken()
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	p.declare(stmt.Name, false)
+
+	stmt.Function = &ast.FunctionLiteral{Token: stmt.Token}
+	if !p.parseFunctionSignature(stmt.Function) {
 		return nil
 	}
 
+	return stmt
+}
+
+// parseFunctionSignature parses the parameter list and body that follow
+// 'fn' (or the function name), with curToken just before the '('.
+func (p *Parser) parseFunctionSignature(lit *ast.FunctionLiteral) bool {
+	if !p.expectPeek(token.LPAREN) {
+		return false
+	}
+
+	p.pushScope()
+	defer p.popScope()
+
+	if !p.parseFunctionParameters(lit) {
+		return false
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return false
+	}
+
 	lit.Body = p.parseBlockStatement()
 
-	return lit
+	return true
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	identifiers := []*ast.Identifier{}
+// parseFunctionParameters parses a parameter list into lit. Parameters may
+// have a default value (y = 10), and a trailing ...name collects the
+// remaining arguments.
+func (p *Parser) parseFunctionParameters(lit *ast.FunctionLiteral) bool {
+	lit.Parameters = []*ast.Identifier{}
+	lit.Defaults = []ast.Expression{}
 
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return identifiers
+		return true
 	}
 
-	p.nextToken()
+	for {
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return false
+			}
+			lit.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			p.declare(lit.Rest, false)
+			break
+		}
 
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	identifiers = append(identifiers, ident)
+		if !p.expectPeek(token.IDENT) {
+			return false
+		}
+		param := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		p.declare(param, false)
+		lit.Parameters = append(lit.Parameters, param)
 
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
-		p.nextToken()
-		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		identifiers = append(identifiers, ident)
-	}
+		var def ast.Expression
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+			def = p.parseExpression(LOWEST)
+		}
+		lit.Defaults = append(lit.Defaults, def)
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
 	}
 
-	return identifiers
+	return p.expectPeek(token.RPAREN)
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
@@ -743,12 +1623,12 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 	}
 
 	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
+	args = append(args, p.parseListElement())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		args = append(args, p.parseListElement())
 	}
 
 	if !p.expectPeek(token.RPAREN) {
@@ -757,3 +1637,98 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 
 	return args
 }
+
+// parseMatchExpression parses: match (subject) { pattern, pattern -> result, else -> result }
+// Arms are separated by commas or newlines; a result is either an expression or a block.
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expr := &ast.MatchExpr{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	expr.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	p.nextToken() // consume the opening brace
+
+	for {
+		for p.curTokenIs(token.COMMA) || p.curTokenIs(token.SEMICOLON) || p.curTokenIs(token.NEWLINE) {
+			p.nextToken()
+		}
+		if p.curTokenIs(token.RBRACE) {
+			break
+		}
+		if p.curTokenIs(token.EOF) {
+			p.addError("unterminated match expression")
+			return nil
+		}
+
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		expr.Arms = append(expr.Arms, arm)
+		p.nextToken() // move past the arm
+	}
+
+	return expr
+}
+
+// parseMatchArm parses a single arm: pattern[, pattern...] -> result or else -> result
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Token: p.curToken}
+
+	if p.curTokenIs(token.ELSE) {
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+	} else {
+		// A pattern is any expression: a value, an object shape or a range
+		arm.Patterns = append(arm.Patterns, p.parseExpression(LOWEST))
+		for p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+			p.nextToken()
+			arm.Patterns = append(arm.Patterns, p.parseExpression(LOWEST))
+		}
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+	}
+
+	p.nextToken()
+	if p.curTokenIs(token.LBRACE) {
+		arm.Body = p.parseBlockStatement()
+		return arm
+	}
+
+	bodyToken := p.curToken
+	value := p.parseExpression(LOWEST)
+	arm.Body = &ast.BlockStatement{
+		Token:      bodyToken,
+		Statements: []ast.Statement{&ast.ExpressionStatement{Token: bodyToken, Expression: value}},
+	}
+
+	return arm
+}
+
+// parseRangeExpression parses: start..end or start..<end
+func (p *Parser) parseRangeExpression(start ast.Expression) ast.Expression {
+	rng := &ast.RangeExpr{
+		Token:     p.curToken,
+		Start:     start,
+		Inclusive: p.curTokenIs(token.DOT_DOT),
+	}
+
+	p.nextToken()
+	rng.End = p.parseExpression(RANGE)
+
+	return rng
+}
@@ -41,7 +41,7 @@ func TestConcurrency_ParallelExecution(t *testing.T) {
 				}
 
 				// Verify result is correct
-				if result.Value != float64(15) {
+				if result.Value != int64(15) {
 					t.Errorf("goroutine %d, iteration %d: expected 15, got %v", id, j, result.Value)
 				}
 			}
@@ -121,7 +121,7 @@ func TestConcurrency_ParallelWithBindings(t *testing.T) {
 				return
 			}
 
-			expected := float64(id*5 + 10)
+			expected := int64(id*5 + 10)
 			if result.Value != expected {
 				t.Errorf("goroutine %d: expected %v, got %v", id, expected, result.Value)
 			}
@@ -241,7 +241,7 @@ func TestLimits_LargeStrings(t *testing.T) {
 		t.Fatalf("Large string test failed: %v", result.Errors)
 	}
 
-	if result.Value != float64(10000) {
+	if result.Value != int64(10000) {
 		t.Errorf("Expected 10000, got %v", result.Value)
 	}
 
@@ -368,7 +368,7 @@ func TestTimeout_LongLoopWithContext(t *testing.T) {
 		if len(result.Errors) > 0 {
 			t.Fatalf("Loop test failed: %v", result.Errors)
 		}
-		if result.Value != float64(100) {
+		if result.Value != int64(100) {
 			t.Errorf("Expected 100, got %v", result.Value)
 		}
 		t.Log("Nested loops test passed (100 iterations)")
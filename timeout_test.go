@@ -25,7 +25,7 @@ func TestTimeout_SimpleScriptCompletesWithinTimeout(t *testing.T) {
 		t.Fatalf("Expected success, got errors: %v", result.Errors)
 	}
 
-	if result.Value != float64(3) {
+	if result.Value != int64(3) {
 		t.Errorf("Expected 3, got %v", result.Value)
 	}
 
@@ -89,7 +89,7 @@ func TestTimeout_NoTimeoutByDefault(t *testing.T) {
 		t.Fatalf("Default (no timeout) should not cause errors: %v", result.Errors)
 	}
 
-	if result.Value != float64(15) {
+	if result.Value != int64(15) {
 		t.Errorf("Expected 15, got %v", result.Value)
 	}
 
@@ -114,7 +114,7 @@ func TestTimeout_ZeroMeansNoTimeout(t *testing.T) {
 		t.Fatalf("Zero timeout should not cause errors: %v", result.Errors)
 	}
 
-	if result.Value != float64(15) {
+	if result.Value != int64(15) {
 		t.Errorf("Expected 15, got %v", result.Value)
 	}
 
@@ -142,7 +142,7 @@ func TestTimeout_CombinedWithMaxOperations(t *testing.T) {
 		t.Fatalf("Should complete with generous limits: %v", result.Errors)
 	}
 
-	if result.Value != float64(55) {
+	if result.Value != int64(55) {
 		t.Errorf("Expected 55, got %v", result.Value)
 	}
 
@@ -169,7 +169,7 @@ func TestTimeout_NestedLoopsWithTimeout(t *testing.T) {
 		t.Fatalf("Nested loops should complete within timeout: %v", result.Errors)
 	}
 
-	if result.Value != float64(9) {
+	if result.Value != int64(9) {
 		t.Errorf("Expected 9, got %v", result.Value)
 	}
 
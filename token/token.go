@@ -12,7 +12,7 @@ const (
 
 	// Identifiers and literals
 	IDENT           Type = "IDENT"           // variable names
-	NUMBER          Type = "NUMBER"          // 123, 45.67
+	NUMBER          Type = "NUMBER"          // 123, 45.67, 1e6, 0xFF, 1_000, 19.99d
 	STRING          Type = "STRING"          // "hello"
 	STRING_TEMPLATE Type = "STRING_TEMPLATE" // "hello ${name}"
 
@@ -23,6 +23,24 @@ const (
 	ASTERISK Type = "*"
 	SLASH    Type = "/"
 	PERCENT  Type = "%"
+	POWER    Type = "**"
+
+	// Bitwise
+	BIT_AND     Type = "&"
+	BIT_OR      Type = "|"
+	BIT_XOR     Type = "^"
+	BIT_NOT     Type = "~"
+	SHIFT_LEFT  Type = "<<"
+	SHIFT_RIGHT Type = ">>"
+
+	// Compound assignment and update
+	PLUS_ASSIGN     Type = "+="
+	MINUS_ASSIGN    Type = "-="
+	ASTERISK_ASSIGN Type = "*="
+	SLASH_ASSIGN    Type = "/="
+	PERCENT_ASSIGN  Type = "%="
+	INCREMENT       Type = "++"
+	DECREMENT       Type = "--"
 
 	// Comparison
 	EQ     Type = "=="
@@ -37,10 +55,22 @@ const (
 	OR  Type = "||"
 	NOT Type = "!"
 
+	// Match and ranges
+	ARROW      Type = "->"  // match arm separator
+	DOT_DOT    Type = ".."  // inclusive range
+	DOT_DOT_LT Type = "..<" // exclusive range
+	ELLIPSIS   Type = "..." // spread and rest
+
 	// Null-safety operators
 	SAFE_ACCESS Type = "?." // Optional chaining
 	ELVIS       Type = "?:" // Null coalescing
 
+	// Conditional
+	QUESTION Type = "?" // cond ? a : b
+
+	// Lambdas
+	FAT_ARROW Type = "=>" // x => expr
+
 	// Delimiters
 	COMMA     Type = ","
 	SEMICOLON Type = ";"
@@ -54,17 +84,27 @@ const (
 	DOT       Type = "."
 
 	// Keywords
-	LET    Type = "LET"
-	IF     Type = "IF"
-	ELSE   Type = "ELSE"
-	TRUE   Type = "TRUE"
-	FALSE  Type = "FALSE"
-	NULL   Type = "NULL"
-	RETURN Type = "RETURN"
-	FOR    Type = "FOR"
-	IN     Type = "IN"
-	FN     Type = "FN"
-	WHILE  Type = "WHILE"
+	LET      Type = "LET"
+	CONST    Type = "CONST"
+	IF       Type = "IF"
+	ELSE     Type = "ELSE"
+	TRUE     Type = "TRUE"
+	FALSE    Type = "FALSE"
+	NULL     Type = "NULL"
+	RETURN   Type = "RETURN"
+	FOR      Type = "FOR"
+	IN       Type = "IN"
+	FN       Type = "FN"
+	WHILE    Type = "WHILE"
+	BREAK    Type = "BREAK"
+	CONTINUE Type = "CONTINUE"
+	MATCH    Type = "MATCH"
+	TRY      Type = "TRY"
+	CATCH    Type = "CATCH"
+	FINALLY  Type = "FINALLY"
+	THROW    Type = "THROW"
+	IMPORT   Type = "IMPORT"
+	EXPORT   Type = "EXPORT"
 )
 
 // Token represents a single token with its type, literal value, and position.
@@ -73,6 +113,7 @@ type Token struct {
 	Literal string
 	Line    int
 	Column  int
+	Doc     string // text of the /// comments just before the token
 }
 
 // LookupIdent checks if an identifier is a keyword and returns the appropriate token type.
@@ -81,6 +122,8 @@ func LookupIdent(ident string) Type {
 	switch ident {
 	case "let":
 		return LET
+	case "const":
+		return CONST
 	case "if":
 		return IF
 	case "else":
@@ -101,6 +144,24 @@ func LookupIdent(ident string) Type {
 		return FN
 	case "while":
 		return WHILE
+	case "break":
+		return BREAK
+	case "continue":
+		return CONTINUE
+	case "match":
+		return MATCH
+	case "try":
+		return TRY
+	case "catch":
+		return CATCH
+	case "finally":
+		return FINALLY
+	case "throw":
+		return THROW
+	case "import":
+		return IMPORT
+	case "export":
+		return EXPORT
 	default:
 		return IDENT
 	}
@@ -109,7 +170,7 @@ func LookupIdent(ident string) Type {
 // CanEndStatement returns true if this token type can end a statement (for ASI).
 func (t Type) CanEndStatement() bool {
 	switch t {
-	case IDENT, NUMBER, STRING, STRING_TEMPLATE, TRUE, FALSE, NULL, RPAREN, RBRACE, RBRACKET:
+	case IDENT, NUMBER, STRING, STRING_TEMPLATE, TRUE, FALSE, NULL, RPAREN, RBRACE, RBRACKET, BREAK, CONTINUE, INCREMENT, DECREMENT:
 		return true
 	default:
 		return false
@@ -125,3 +186,13 @@ func (t Type) IsOperatorContinuation() bool {
 		return false
 	}
 }
+
+// IsAssignment returns true if this token type is = or a compound assignment operator.
+func (t Type) IsAssignment() bool {
+	switch t {
+	case ASSIGN, PLUS_ASSIGN, MINUS_ASSIGN, ASTERISK_ASSIGN, SLASH_ASSIGN, PERCENT_ASSIGN:
+		return true
+	default:
+		return false
+	}
+}